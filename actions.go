@@ -0,0 +1,146 @@
+package main
+
+import "strings"
+
+// INPUT ACTIONS
+// the game asks whether an action is happening, not whether a key is down,
+// so every control can be rebound. this file is just the list of actions and their default bindings,
+// input_state.go works out whether the inputs bound to them are down.
+
+// Action, something the player can do, named the way it is in the settings file.
+type Action string
+
+const (
+	ACTION_MOVE_FORWARD Action = "move_forward"
+	ACTION_MOVE_BACK    Action = "move_back"
+	ACTION_MOVE_LEFT    Action = "move_left"
+	ACTION_MOVE_RIGHT   Action = "move_right"
+	ACTION_JUMP         Action = "jump"
+	ACTION_SNEAK        Action = "sneak"
+	ACTION_ROTATE_LEFT  Action = "rotate_left"
+	ACTION_ROTATE_RIGHT Action = "rotate_right"
+	ACTION_PAUSE        Action = "pause"
+	ACTION_DEBUG        Action = "debug"
+	ACTION_XRAY         Action = "xray"
+	ACTION_DEPTH_SHIFT  Action = "depth_shift"
+	ACTION_CUTAWAY      Action = "cutaway"
+	ACTION_CUTAWAY_UP   Action = "cutaway_up"
+	ACTION_CUTAWAY_DOWN Action = "cutaway_down"
+	ACTION_ZOOM_IN      Action = "zoom_in"
+	ACTION_ZOOM_OUT     Action = "zoom_out"
+	ACTION_MAP          Action = "map"
+	ACTION_SCREENSHOT   Action = "screenshot"
+	ACTION_POSTER       Action = "poster"
+	ACTION_PLACE        Action = "place"
+	ACTION_BREAK        Action = "break"
+
+	ACTION_MENU_UP      Action = "menu_up"
+	ACTION_MENU_DOWN    Action = "menu_down"
+	ACTION_MENU_LEFT    Action = "menu_left"
+	ACTION_MENU_RIGHT   Action = "menu_right"
+	ACTION_MENU_CONFIRM Action = "menu_confirm"
+	ACTION_MENU_BACK    Action = "menu_back"
+)
+
+// Binding, the inputs that trigger an action.
+// keys use ebiten's key names ("W", "ArrowUp", "F3"), mouse buttons are "Left", "Right", "Middle",
+// or "WheelUp" and "WheelDown" which happen once for every notch the wheel turns,
+// and gamepad buttons use the standard layout names in gamepadButtonNames, or stickDirectionNames.
+type Binding struct {
+	Keys    []string `json:"keys,omitempty"`
+	Mouse   []string `json:"mouse,omitempty"`
+	Gamepad []string `json:"gamepad,omitempty"`
+}
+
+// Trigger, when an action happens while its input is down.
+type Trigger int
+
+const (
+	TRIGGER_PRESS  Trigger = iota // once, on the frame the input is pressed
+	TRIGGER_HOLD                  // every frame the input is held
+	TRIGGER_REPEAT                // when pressed, then over and over once it has been held a while
+)
+
+// ActionInfo, how an action behaves and what it's bound to by default.
+type ActionInfo struct {
+	Action  Action
+	Label   string
+	Trigger Trigger
+	Default Binding
+}
+
+// every action, in the order the controls menu shows them
+var actions = []ActionInfo{
+	{ACTION_MOVE_FORWARD, "Move Forward", TRIGGER_HOLD, Binding{Keys: []string{"W"}, Gamepad: []string{"DpadUp"}}},
+	{ACTION_MOVE_BACK, "Move Back", TRIGGER_HOLD, Binding{Keys: []string{"S"}, Gamepad: []string{"DpadDown"}}},
+	{ACTION_MOVE_LEFT, "Move Left", TRIGGER_HOLD, Binding{Keys: []string{"A"}, Gamepad: []string{"DpadLeft"}}},
+	{ACTION_MOVE_RIGHT, "Move Right", TRIGGER_HOLD, Binding{Keys: []string{"D"}, Gamepad: []string{"DpadRight"}}},
+	{ACTION_JUMP, "Jump / Fly Up", TRIGGER_HOLD, Binding{Keys: []string{"Space"}, Gamepad: []string{"A"}}},
+	{ACTION_SNEAK, "Sneak / Fly Down", TRIGGER_HOLD, Binding{Keys: []string{"ShiftLeft"}, Gamepad: []string{"B"}}},
+	{ACTION_ROTATE_LEFT, "Rotate Left", TRIGGER_PRESS, Binding{Keys: []string{"Q", "ArrowLeft"}, Gamepad: []string{"LeftShoulder"}}},
+	{ACTION_ROTATE_RIGHT, "Rotate Right", TRIGGER_PRESS, Binding{Keys: []string{"E", "ArrowRight"}, Gamepad: []string{"RightShoulder"}}},
+	{ACTION_PAUSE, "Pause", TRIGGER_PRESS, Binding{Keys: []string{"Escape"}, Gamepad: []string{"Start"}}},
+	{ACTION_DEBUG, "Debug Overlay", TRIGGER_PRESS, Binding{Keys: []string{"F3"}, Gamepad: []string{"Back"}}},
+	{ACTION_XRAY, "X-Ray (Debug)", TRIGGER_PRESS, Binding{Keys: []string{"F4"}}},
+	{ACTION_DEPTH_SHIFT, "Depth Shift", TRIGGER_PRESS, Binding{Keys: []string{"Backslash"}}},
+	{ACTION_CUTAWAY, "Cut-Away", TRIGGER_PRESS, Binding{Keys: []string{"V"}, Gamepad: []string{"Y"}}},
+	{ACTION_CUTAWAY_UP, "Cut-Away Up", TRIGGER_REPEAT, Binding{Keys: []string{"PageUp"}, Mouse: []string{"WheelUp"}}},
+	{ACTION_CUTAWAY_DOWN, "Cut-Away Down", TRIGGER_REPEAT, Binding{Keys: []string{"PageDown"}, Mouse: []string{"WheelDown"}}},
+	{ACTION_ZOOM_IN, "Zoom In", TRIGGER_PRESS, Binding{Keys: []string{"Equal", "NumpadAdd"}, Gamepad: []string{"RightStick"}}},
+	{ACTION_ZOOM_OUT, "Zoom Out", TRIGGER_PRESS, Binding{Keys: []string{"Minus", "NumpadSubtract"}, Gamepad: []string{"LeftStick"}}},
+	{ACTION_MAP, "Map", TRIGGER_PRESS, Binding{Keys: []string{"M"}}},
+	{ACTION_SCREENSHOT, "Screenshot", TRIGGER_PRESS, Binding{Keys: []string{"F2"}}},
+	{ACTION_POSTER, "Poster Screenshot", TRIGGER_PRESS, Binding{Keys: []string{"F9"}}},
+	{ACTION_PLACE, "Place Block", TRIGGER_PRESS, Binding{Mouse: []string{"Right"}, Gamepad: []string{"RightTrigger"}}},
+	{ACTION_BREAK, "Break Block", TRIGGER_PRESS, Binding{Mouse: []string{"Left"}, Gamepad: []string{"LeftTrigger"}}},
+
+	{ACTION_MENU_UP, "Menu Up", TRIGGER_REPEAT, Binding{Keys: []string{"ArrowUp"}, Gamepad: []string{"DpadUp", "LeftStickUp"}}},
+	{ACTION_MENU_DOWN, "Menu Down", TRIGGER_REPEAT, Binding{Keys: []string{"ArrowDown"}, Gamepad: []string{"DpadDown", "LeftStickDown"}}},
+	{ACTION_MENU_LEFT, "Menu Left", TRIGGER_REPEAT, Binding{Keys: []string{"ArrowLeft"}, Gamepad: []string{"DpadLeft", "LeftStickLeft"}}},
+	{ACTION_MENU_RIGHT, "Menu Right", TRIGGER_REPEAT, Binding{Keys: []string{"ArrowRight"}, Gamepad: []string{"DpadRight", "LeftStickRight"}}},
+	{ACTION_MENU_CONFIRM, "Menu Confirm", TRIGGER_PRESS, Binding{Keys: []string{"Enter"}, Gamepad: []string{"A"}}},
+	{ACTION_MENU_BACK, "Menu Back", TRIGGER_PRESS, Binding{Keys: []string{"Escape"}, Gamepad: []string{"B"}}},
+}
+
+// get the info for an action
+func actionInfo(action Action) (info ActionInfo, ok bool) {
+	for _, info := range actions {
+		if info.Action == action {
+			return info, true
+		}
+	}
+	return ActionInfo{}, false
+}
+
+// the names of every input in a binding, the same names an InputSource reports them by
+func bindingInputs(binding Binding) []string {
+	var names []string
+	names = append(names, binding.Keys...)
+	for _, name := range binding.Mouse {
+		names = append(names, "Mouse "+name)
+	}
+	for _, name := range binding.Gamepad {
+		names = append(names, "Pad "+name)
+	}
+	return names
+}
+
+// turn an input name back into a binding with just that input in it
+func inputBinding(name string) Binding {
+	if button, ok := strings.CutPrefix(name, "Mouse "); ok {
+		return Binding{Mouse: []string{button}}
+	}
+	if button, ok := strings.CutPrefix(name, "Pad "); ok {
+		return Binding{Gamepad: []string{button}}
+	}
+	return Binding{Keys: []string{name}}
+}
+
+// the bindings used when the settings file doesn't have any
+func defaultBindings() map[Action]Binding {
+	bindings := make(map[Action]Binding, len(actions))
+	for _, info := range actions {
+		bindings[info.Action] = info.Default
+	}
+	return bindings
+}
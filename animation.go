@@ -0,0 +1,236 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"time"
+)
+
+// ANIMATION
+// player animations come from the aseprite file the player atlas is exported from.
+// every aseprite frame is one sprite sized cell of the atlas, left to right then top to bottom,
+// and tags name the animations as "<animation>_<facing>", like "walk_south" or "idle_northeast".
+// a tag without a facing is used for every facing that doesn't have its own.
+
+// the facings a sprite can have, going round clockwise from facing the camera
+var facings = []string{"south", "southwest", "west", "northwest", "north", "northeast", "east", "southeast"}
+
+// aseprite tag loop directions
+const (
+	ASEPRITE_FORWARD = iota
+	ASEPRITE_REVERSE
+	ASEPRITE_PING_PONG
+	ASEPRITE_PING_PONG_REVERSE
+)
+
+// AsepriteTag, a named range of frames.
+type AsepriteTag struct {
+	Name      string
+	From, To  int
+	Direction int
+}
+
+// AsepriteFile, the parts of an aseprite file animations need.
+type AsepriteFile struct {
+	Width, Height int
+	Durations     []time.Duration // how long each frame shows for
+	Tags          []AsepriteTag
+}
+
+// read the frames and tags out of an aseprite file
+func readAseprite(path string) (AsepriteFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return AsepriteFile{}, err
+	}
+	return parseAseprite(data)
+}
+
+// parse an aseprite file. only the header, frame headers and tag chunks are read, the pixels come from the exported atlas.
+// see https://github.com/aseprite/aseprite/blob/main/docs/ase-file-specs.md
+func parseAseprite(data []byte) (AsepriteFile, error) {
+	var file AsepriteFile
+	if len(data) < 128 || binary.LittleEndian.Uint16(data[4:]) != 0xA5E0 {
+		return file, errors.New("not an aseprite file")
+	}
+	frames := int(binary.LittleEndian.Uint16(data[6:]))
+	file.Width = int(binary.LittleEndian.Uint16(data[8:]))
+	file.Height = int(binary.LittleEndian.Uint16(data[10:]))
+
+	offset := 128
+	for frame := 0; frame < frames; frame++ {
+		if offset+16 > len(data) || binary.LittleEndian.Uint16(data[offset+4:]) != 0xF1FA {
+			return file, fmt.Errorf("frame %d is cut off", frame)
+		}
+		frameSize := int(binary.LittleEndian.Uint32(data[offset:]))
+		chunks := int(binary.LittleEndian.Uint16(data[offset+6:]))
+		if newChunks := int(binary.LittleEndian.Uint32(data[offset+12:])); newChunks != 0 {
+			chunks = newChunks
+		}
+		file.Durations = append(file.Durations, time.Duration(binary.LittleEndian.Uint16(data[offset+8:]))*time.Millisecond)
+		if frameSize < 16 || offset+frameSize > len(data) {
+			return file, fmt.Errorf("frame %d is cut off", frame)
+		}
+
+		chunk := offset + 16
+		for i := 0; i < chunks; i++ {
+			if chunk+6 > offset+frameSize {
+				return file, fmt.Errorf("chunk %d of frame %d is cut off", i, frame)
+			}
+			chunkSize := int(binary.LittleEndian.Uint32(data[chunk:]))
+			if chunkSize < 6 || chunk+chunkSize > offset+frameSize {
+				return file, fmt.Errorf("chunk %d of frame %d is cut off", i, frame)
+			}
+			if binary.LittleEndian.Uint16(data[chunk+4:]) == 0x2018 {
+				tags, err := parseAsepriteTags(data[chunk+6 : chunk+chunkSize])
+				if err != nil {
+					return file, err
+				}
+				file.Tags = append(file.Tags, tags...)
+			}
+			chunk += chunkSize
+		}
+		offset += frameSize
+	}
+	return file, nil
+}
+
+// parse the body of a tags chunk
+func parseAsepriteTags(data []byte) ([]AsepriteTag, error) {
+	if len(data) < 10 {
+		return nil, errors.New("tags chunk is cut off")
+	}
+	count := int(binary.LittleEndian.Uint16(data))
+	tags := make([]AsepriteTag, 0, count)
+	offset := 10
+	for i := 0; i < count; i++ {
+		// from, to, direction, repeat, reserved, color, name length, then the name
+		if offset+19 > len(data) {
+			return nil, errors.New("tags chunk is cut off")
+		}
+		nameLength := int(binary.LittleEndian.Uint16(data[offset+17:]))
+		if offset+19+nameLength > len(data) {
+			return nil, errors.New("tags chunk is cut off")
+		}
+		tags = append(tags, AsepriteTag{
+			Name:      string(data[offset+19 : offset+19+nameLength]),
+			From:      int(binary.LittleEndian.Uint16(data[offset:])),
+			To:        int(binary.LittleEndian.Uint16(data[offset+2:])),
+			Direction: int(data[offset+4]),
+		})
+		offset += 19 + nameLength
+	}
+	return tags, nil
+}
+
+// Animation, frames of the atlas in the order they play, and how long each one shows for.
+type Animation struct {
+	Frames    []int
+	Durations []time.Duration
+}
+
+// every animation, by tag name
+type AnimationSet map[string]Animation
+
+// turn the tags of an aseprite file into animations
+func animationsFromAseprite(file AsepriteFile) AnimationSet {
+	set := make(AnimationSet, len(file.Tags))
+	for _, tag := range file.Tags {
+		if tag.From < 0 || tag.To >= len(file.Durations) || tag.From > tag.To {
+			continue
+		}
+		var frames []int
+		for frame := tag.From; frame <= tag.To; frame++ {
+			frames = append(frames, frame)
+		}
+		if tag.Direction == ASEPRITE_REVERSE || tag.Direction == ASEPRITE_PING_PONG_REVERSE {
+			for i, j := 0, len(frames)-1; i < j; i, j = i+1, j-1 {
+				frames[i], frames[j] = frames[j], frames[i]
+			}
+		}
+		// ping pong goes back without showing either end twice
+		if tag.Direction == ASEPRITE_PING_PONG || tag.Direction == ASEPRITE_PING_PONG_REVERSE {
+			for i := len(frames) - 2; i > 0; i-- {
+				frames = append(frames, frames[i])
+			}
+		}
+
+		animation := Animation{Frames: frames}
+		for _, frame := range frames {
+			animation.Durations = append(animation.Durations, file.Durations[frame])
+		}
+		// lower case, so tags can be named however the artist likes
+		set[strings.ToLower(tag.Name)] = animation
+	}
+	return set
+}
+
+// find the animation for a facing. diagonals fall back to the facings either side of them,
+// then a tag without a facing, then idle.
+func (set AnimationSet) Find(name, facing string) (Animation, bool) {
+	candidates := []string{facing}
+	if index := indexOf(facings, facing); index%2 == 1 {
+		candidates = append(candidates, facings[index-1], facings[(index+1)%len(facings)])
+	}
+	for _, name := range []string{name, "idle"} {
+		for _, candidate := range candidates {
+			if animation, ok := set[name+"_"+candidate]; ok {
+				return animation, true
+			}
+		}
+		if animation, ok := set[name]; ok {
+			return animation, true
+		}
+	}
+	return Animation{}, false
+}
+
+// the frame showing a while into the animation, looping round
+func (animation Animation) FrameAt(elapsed time.Duration) int {
+	var total time.Duration
+	for _, duration := range animation.Durations {
+		total += duration
+	}
+	if total <= 0 {
+		return animation.Frames[0]
+	}
+	elapsed %= total
+	for i, duration := range animation.Durations {
+		if elapsed < duration {
+			return animation.Frames[i]
+		}
+		elapsed -= duration
+	}
+	return animation.Frames[len(animation.Frames)-1]
+}
+
+// the facing for a movement on the screen, x right and y down. down the screen is south, towards the camera.
+func facingFromScreen(x, y float32) string {
+	angle := math.Atan2(float64(-x), float64(y)) // 0 is down the screen, going clockwise
+	sector := int(math.Round(angle/(math.Pi/4))) % len(facings)
+	if sector < 0 {
+		sector += len(facings)
+	}
+	return facings[sector]
+}
+
+// where in the atlas a frame is, for sprites of a size in an atlas of a width
+func atlasFrameRect(frame, spriteWidth, spriteHeight, atlasWidth int) [4]int {
+	columns := max(atlasWidth/spriteWidth, 1)
+	x, y := frame%columns*spriteWidth, frame/columns*spriteHeight
+	return [4]int{x, y, x + spriteWidth, y + spriteHeight}
+}
+
+// the index of an item in a list, or -1
+func indexOf[T comparable](items []T, item T) int {
+	for i, candidate := range items {
+		if candidate == item {
+			return i
+		}
+	}
+	return -1
+}
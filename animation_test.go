@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/binary"
+	"image/png"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// build an aseprite file with frames of some durations and a tags chunk in the first frame
+func buildAseprite(durations []uint16, tags []AsepriteTag) []byte {
+	var tagsChunk []byte
+	tagsChunk = binary.LittleEndian.AppendUint16(tagsChunk, uint16(len(tags)))
+	tagsChunk = append(tagsChunk, make([]byte, 8)...)
+	for _, tag := range tags {
+		tagsChunk = binary.LittleEndian.AppendUint16(tagsChunk, uint16(tag.From))
+		tagsChunk = binary.LittleEndian.AppendUint16(tagsChunk, uint16(tag.To))
+		tagsChunk = append(tagsChunk, byte(tag.Direction))
+		tagsChunk = append(tagsChunk, make([]byte, 2+6+3+1)...) // repeat, reserved, color, extra
+		tagsChunk = binary.LittleEndian.AppendUint16(tagsChunk, uint16(len(tag.Name)))
+		tagsChunk = append(tagsChunk, tag.Name...)
+	}
+
+	data := make([]byte, 128)
+	binary.LittleEndian.PutUint16(data[4:], 0xA5E0)
+	binary.LittleEndian.PutUint16(data[6:], uint16(len(durations)))
+	binary.LittleEndian.PutUint16(data[8:], 32)
+	binary.LittleEndian.PutUint16(data[10:], 48)
+	for i, duration := range durations {
+		var chunks []byte
+		if i == 0 {
+			chunks = binary.LittleEndian.AppendUint32(chunks, uint32(6+len(tagsChunk)))
+			chunks = binary.LittleEndian.AppendUint16(chunks, 0x2018)
+			chunks = append(chunks, tagsChunk...)
+		}
+		frame := make([]byte, 16)
+		binary.LittleEndian.PutUint32(frame, uint32(16+len(chunks)))
+		binary.LittleEndian.PutUint16(frame[4:], 0xF1FA)
+		binary.LittleEndian.PutUint16(frame[8:], duration)
+		if i == 0 {
+			binary.LittleEndian.PutUint32(frame[12:], 1)
+		}
+		data = append(data, append(frame, chunks...)...)
+	}
+	binary.LittleEndian.PutUint32(data, uint32(len(data)))
+	return data
+}
+
+func TestParseAseprite(t *testing.T) {
+	tags := []AsepriteTag{
+		{Name: "Walk_South", From: 0, To: 2, Direction: ASEPRITE_FORWARD},
+		{Name: "idle", From: 3, To: 3, Direction: ASEPRITE_FORWARD},
+		{Name: "swim_east", From: 0, To: 2, Direction: ASEPRITE_PING_PONG},
+	}
+	file, err := parseAseprite(buildAseprite([]uint16{100, 50, 100, 200}, tags))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(file.Tags, tags) {
+		t.Errorf("tags = %+v, want %+v", file.Tags, tags)
+	}
+	want := []time.Duration{100 * time.Millisecond, 50 * time.Millisecond, 100 * time.Millisecond, 200 * time.Millisecond}
+	if !reflect.DeepEqual(file.Durations, want) {
+		t.Errorf("durations = %v, want %v", file.Durations, want)
+	}
+
+	set := animationsFromAseprite(file)
+	if frames := set["swim_east"].Frames; !reflect.DeepEqual(frames, []int{0, 1, 2, 1}) {
+		t.Errorf("ping pong frames = %v, want 0 1 2 1", frames)
+	}
+
+	walk, ok := set.Find("walk", "south")
+	if !ok {
+		t.Fatal("walk south not found")
+	}
+	for _, test := range []struct {
+		elapsed time.Duration
+		frame   int
+	}{{0, 0}, {99 * time.Millisecond, 0}, {100 * time.Millisecond, 1}, {160 * time.Millisecond, 2}, {250 * time.Millisecond, 0}} {
+		if frame := walk.FrameAt(test.elapsed); frame != test.frame {
+			t.Errorf("walk at %v = frame %d, want %d", test.elapsed, frame, test.frame)
+		}
+	}
+
+	// diagonals use the facings next to them, and anything missing is idle
+	if animation, _ := set.Find("walk", "southeast"); !reflect.DeepEqual(animation, walk) {
+		t.Error("walk southeast didn't fall back to walk south")
+	}
+	if animation, _ := set.Find("jump", "north"); !reflect.DeepEqual(animation.Frames, []int{3}) {
+		t.Errorf("jump north = %v, want idle", animation.Frames)
+	}
+	if _, ok := (AnimationSet{}).Find("walk", "south"); ok {
+		t.Error("found an animation in an empty set")
+	}
+
+	if _, err := parseAseprite(buildAseprite([]uint16{100}, tags)[:140]); err == nil {
+		t.Error("parsed a cut off file")
+	}
+}
+
+func TestPlayerAtlasAseprite(t *testing.T) {
+	// the shipped atlas has idle and walk for every facing, and jump and swim for all of them
+	file, err := readAseprite("assets/player_atlas.aseprite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file.Width != playerSpriteWidth || file.Height != playerSpriteHeight {
+		t.Errorf("aseprite frames are %dx%d, want %dx%d", file.Width, file.Height, playerSpriteWidth, playerSpriteHeight)
+	}
+	set := animationsFromAseprite(file)
+	want := []string{"jump", "swim"}
+	for _, facing := range facings {
+		want = append(want, "idle_"+facing, "walk_"+facing)
+	}
+	for _, name := range want {
+		if _, ok := set[name]; !ok {
+			t.Errorf("no %s tag in the player atlas", name)
+		}
+	}
+
+	// every frame has to be in the exported atlas
+	atlasFile, err := os.Open("assets/player_atlas.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer atlasFile.Close()
+	atlas, err := png.DecodeConfig(atlasFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rect := atlasFrameRect(len(file.Durations)-1, playerSpriteWidth, playerSpriteHeight, atlas.Width); rect[2] > atlas.Width || rect[3] > atlas.Height {
+		t.Errorf("last frame is at %v, outside the %dx%d atlas", rect, atlas.Width, atlas.Height)
+	}
+	if rect := atlasFrameRect(0, playerSpriteWidth, playerSpriteHeight, atlas.Width); rect != playerTextureMap["Default"] {
+		t.Errorf("frame 0 is at %v, want the default texture at %v", rect, playerTextureMap["Default"])
+	}
+}
+
+func TestFacingFromScreen(t *testing.T) {
+	tests := map[[2]float32]string{
+		{0, 1}: "south", {-1, 1}: "southwest", {-1, 0}: "west", {-1, -1}: "northwest",
+		{0, -1}: "north", {1, -1}: "northeast", {1, 0}: "east", {1, 1}: "southeast",
+	}
+	for move, want := range tests {
+		if facing := facingFromScreen(move[0], move[1]); facing != want {
+			t.Errorf("facingFromScreen(%v) = %s, want %s", move, facing, want)
+		}
+	}
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// BACKUPS
+// snapshots of a save are zipped into save/.backups/<world>/, named by when they were made and why.
+// backups made automatically are pruned by the retention policy, anything else is kept until deleted by hand.
+
+// the timestamp at the start of a backup file name
+const backupTimeLayout = "20060102-150405.000"
+
+// BackupPolicy, how often to back up a world while it's open and which automatic backups to keep.
+type BackupPolicy struct {
+	Interval   time.Duration // time between scheduled backups
+	KeepLatest int           // always keep this many of the newest automatic backups
+	KeepDaily  int           // and the newest automatic backup from each of this many days
+}
+
+var backupPolicy = BackupPolicy{Interval: 10 * time.Minute, KeepLatest: 5, KeepDaily: 7}
+
+// reasons for backups that get pruned, everything else was asked for or protects against something going wrong
+var automaticBackupReasons = []string{"load", "scheduled"}
+
+// BackupInfo, a snapshot of a world.
+type BackupInfo struct {
+	Path   string
+	Time   time.Time
+	Reason string
+	Size   int64
+}
+
+// where the backups of a save are kept
+func backupDirectory(savePath string) string {
+	savePath = filepath.Clean(savePath)
+	return filepath.Join(filepath.Dir(savePath), ".backups", filepath.Base(savePath))
+}
+
+// zip up a save, and prune old automatic backups if this is one
+func createBackup(savePath, reason string) (backupPath string, err error) {
+	directory := backupDirectory(savePath)
+	if err = os.MkdirAll(directory, 0755); err != nil {
+		return "", err
+	}
+	backupPath = filepath.Join(directory, fmt.Sprintf("%s-%s.zip", time.Now().Format(backupTimeLayout), reason))
+
+	// zip into a temporary file, so a half written backup never looks like a real one
+	temp, err := os.CreateTemp(directory, ".backup.tmp*")
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if err != nil {
+			temp.Close()
+			os.Remove(temp.Name())
+		}
+	}()
+
+	archive := zip.NewWriter(temp)
+	err = filepath.Walk(savePath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		// skip the lock and any half written files
+		if info.Name() == "session.lock" || strings.Contains(info.Name(), ".tmp") {
+			return nil
+		}
+		relative, err := filepath.Rel(savePath, path)
+		if err != nil || relative == "." {
+			return err
+		}
+
+		// keep empty directories, the game expects terrain/ to be there
+		if info.IsDir() {
+			_, err = archive.Create(filepath.ToSlash(relative) + "/")
+			return err
+		}
+
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(relative)
+		header.Method = zip.Deflate
+		writer, err := archive.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		_, err = io.Copy(writer, file)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	if err = archive.Close(); err != nil {
+		return "", err
+	}
+	if err = temp.Sync(); err != nil {
+		return "", err
+	}
+	if err = temp.Close(); err != nil {
+		return "", err
+	}
+	if err = os.Rename(temp.Name(), backupPath); err != nil {
+		return "", err
+	}
+
+	if contains(automaticBackupReasons, reason) {
+		if pruneErr := pruneBackups(savePath, backupPolicy); pruneErr != nil {
+			log.Printf("ERROR: Failed to prune backups: %v", pruneErr)
+		}
+	}
+	return backupPath, nil
+}
+
+// list the backups of a save, newest first
+func listBackups(savePath string) (backups []BackupInfo, err error) {
+	directory := backupDirectory(savePath)
+	entries, err := os.ReadDir(directory)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".zip") || len(name) < len(backupTimeLayout)+1 {
+			continue
+		}
+		made, err := time.ParseInLocation(backupTimeLayout, name[:len(backupTimeLayout)], time.Local)
+		if err != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		backups = append(backups, BackupInfo{
+			Path:   filepath.Join(directory, name),
+			Time:   made,
+			Reason: strings.TrimSuffix(name[len(backupTimeLayout)+1:], ".zip"),
+			Size:   info.Size(),
+		})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].Time.After(backups[j].Time)
+	})
+	return backups, nil
+}
+
+// delete automatic backups that the policy doesn't keep
+func pruneBackups(savePath string, policy BackupPolicy) error {
+	backups, err := listBackups(savePath)
+	if err != nil {
+		return err
+	}
+
+	automatic := 0
+	days := make(map[string]bool)
+	for _, backup := range backups {
+		if !contains(automaticBackupReasons, backup.Reason) {
+			continue
+		}
+		automatic++
+		day := backup.Time.Format("20060102")
+
+		// newest first, so the first backup seen for a day is the one to keep
+		keep := automatic <= policy.KeepLatest
+		if !days[day] && len(days) < policy.KeepDaily {
+			days[day] = true
+			keep = true
+		}
+		if !keep {
+			if err := os.Remove(backup.Path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// replace a save with a backup. the save is backed up first, so a restore can be undone too.
+func restoreBackup(savePath, backupPath string) (err error) {
+	archive, err := zip.OpenReader(backupPath)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	if pathExists(savePath) {
+		// don't pull the save out from under someone who is playing it
+		lock, err := acquireSaveLock(savePath)
+		if err != nil {
+			return err
+		}
+		// once the swap is done the lock is gone with the old save, and this does nothing
+		defer lock.Release()
+
+		if _, err = createBackup(savePath, "before-restore"); err != nil {
+			return fmt.Errorf("failed to back up save before restoring: %v", err)
+		}
+	}
+
+	// unpack next to the backups and swap it in once everything is there
+	restorePath := filepath.Join(backupDirectory(savePath), ".restoring")
+	os.RemoveAll(restorePath)
+	defer os.RemoveAll(restorePath)
+	for _, file := range archive.File {
+		if err = extractZipFile(file, restorePath); err != nil {
+			return err
+		}
+	}
+	if !pathExists(filepath.Join(restorePath, "world.json")) {
+		return fmt.Errorf("%s is not a world backup", backupPath)
+	}
+
+	// the old lock goes with the old save
+	oldPath := filepath.Join(backupDirectory(savePath), ".replaced")
+	os.RemoveAll(oldPath)
+	if pathExists(savePath) {
+		if err = os.Rename(savePath, oldPath); err != nil {
+			return err
+		}
+	}
+	if err = os.Rename(restorePath, savePath); err != nil {
+		// put the old save back
+		os.Rename(oldPath, savePath)
+		return err
+	}
+	return os.RemoveAll(oldPath)
+}
+
+// unpack one file from a backup
+func extractZipFile(file *zip.File, targetPath string) error {
+	path := filepath.Join(targetPath, filepath.FromSlash(file.Name))
+	// never write outside of the target, whatever the archive says
+	if !strings.HasPrefix(path, filepath.Clean(targetPath)+string(os.PathSeparator)) {
+		return fmt.Errorf("backup contains an invalid path: %s", file.Name)
+	}
+	if file.FileInfo().IsDir() {
+		return os.MkdirAll(path, 0755)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	in, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// back up the open world if it's been long enough since the last one
+func (game *Game) backupIfDue() {
+	if time.Since(game.LastBackup) < backupPolicy.Interval {
+		return
+	}
+	game.LastBackup = time.Now()
+
+	// the backup is what's on disk, so write out anything that has changed first
+	game.saveDirtyChunks()
+	if _, err := createBackup(game.World.SavePath, "scheduled"); err != nil {
+		log.Printf("ERROR: Failed to back up world: %v", err)
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBackupAndRestore(t *testing.T) {
+	savePath := copyFixtureSave(t, "v1")
+
+	backupPath, err := createBackup(savePath, "manual")
+	if err != nil {
+		t.Fatalf("failed to back up: %v", err)
+	}
+
+	// break the world after the backup
+	if err := os.WriteFile(filepath.Join(savePath, "world.json"), []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(savePath, "terrain", "chunk0_0.json")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := restoreBackup(savePath, backupPath); err != nil {
+		t.Fatalf("failed to restore: %v", err)
+	}
+	game := &Game{}
+	if err := game.LoadGame(savePath); err != nil {
+		t.Fatalf("failed to load restored save: %v", err)
+	}
+	checkFixtureSave(t, game, savePath)
+	if pathExists(filepath.Join(savePath, "session.lock")) {
+		t.Errorf("restored save is still locked")
+	}
+
+	// the broken world was backed up before it was replaced
+	backups, err := listBackups(savePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 2 || backups[0].Reason != "before-restore" || backups[1].Reason != "manual" {
+		t.Errorf("backups = %+v, expected before-restore then manual", backups)
+	}
+}
+
+func TestRestoreRefusesOpenWorld(t *testing.T) {
+	savePath := copyFixtureSave(t, "v1")
+	backupPath, err := createBackup(savePath, "manual")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lock, err := acquireSaveLock(savePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lock.Release()
+	if err := restoreBackup(savePath, backupPath); err == nil {
+		t.Errorf("restored over a world that is open")
+	}
+}
+
+func TestPruneBackups(t *testing.T) {
+	savePath := copyFixtureSave(t, "v1")
+	directory := backupDirectory(savePath)
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// four scheduled backups a day for three days, and a manual one on the oldest day
+	start := time.Date(2025, 1, 1, 9, 0, 0, 0, time.Local)
+	for day := 0; day < 3; day++ {
+		for hour := 0; hour < 4; hour++ {
+			made := start.AddDate(0, 0, day).Add(time.Duration(hour) * time.Hour)
+			name := made.Format(backupTimeLayout) + "-scheduled.zip"
+			if err := os.WriteFile(filepath.Join(directory, name), nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := os.WriteFile(filepath.Join(directory, start.Format(backupTimeLayout)+"-manual.zip"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := pruneBackups(savePath, BackupPolicy{KeepLatest: 2, KeepDaily: 2}); err != nil {
+		t.Fatal(err)
+	}
+	backups, err := listBackups(savePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the two newest, the newest from the day before, and the manual one
+	expected := []string{"20250103-120000.000", "20250103-110000.000", "20250102-120000.000", "20250101-090000.000"}
+	if len(backups) != len(expected) {
+		t.Fatalf("kept %d backups, expected %d: %+v", len(backups), len(expected), backups)
+	}
+	for i, backup := range backups {
+		if backup.Time.Format(backupTimeLayout) != expected[i] {
+			t.Errorf("backup %d = %s, expected %s", i, backup.Time.Format(backupTimeLayout), expected[i])
+		}
+	}
+}
+
+func TestScheduledBackupIncludesUnsavedChunks(t *testing.T) {
+	savePath := copyFixtureSave(t, "v1")
+	game := &Game{}
+	if err := game.LoadGame(savePath); err != nil {
+		t.Fatal(err)
+	}
+	game.World.generateChunk([2]int{-1, 0}, game.World.ChunkSize, game.World.ChunkSize, game.World.ChunkDepth, defaultVoxelDictionary)
+	if !game.World.SetVoxel(-1, 0, 0, defaultVoxelDictionary.GetVoxelPointerTo("Cobblestone")) {
+		t.Fatal("failed to set voxel")
+	}
+
+	game.backupIfDue()
+	backups, err := listBackups(savePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 1 || backups[0].Reason != "scheduled" {
+		t.Fatalf("backups = %+v, expected one scheduled backup", backups)
+	}
+	archive, err := zip.OpenReader(backups[0].Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer archive.Close()
+	if _, err := archive.Open("terrain/" + chunkFileNameFromCoordinate(-1, 0)); err != nil {
+		t.Errorf("backup is missing the edited chunk: %v", err)
+	}
+}
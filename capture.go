@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"log"
+	"time"
+)
+
+// how long a notice stays on the screen
+const noticeDuration = 4 * time.Second
+
+// pass a message back to the game from the background, dropping it if too many are waiting
+func (game *Game) notify(format string, args ...any) {
+	select {
+	case game.Notices <- fmt.Sprintf(format, args...):
+	default:
+	}
+}
+
+// show the newest notice from the background
+func (game *Game) updateNotices() {
+	for {
+		select {
+		case notice := <-game.Notices:
+			game.Notice, game.NoticeTime = notice, time.Now()
+		default:
+			return
+		}
+	}
+}
+
+// write a screenshot in the background, png encoding a big image takes a while
+func (game *Game) saveScreenshot(img image.Image, seed int64, scale int, kind string) {
+	when := time.Now()
+	go func() {
+		path, err := writeScreenshot(screenshotsPath, upscaleImage(img, scale), when, seed, kind)
+		if err != nil {
+			log.Printf("ERROR: Failed to save screenshot: %v", err)
+			game.notify("Failed to save screenshot: %v", err)
+			return
+		}
+		log.Printf("Saved screenshot to %s", path)
+		game.notify("Saved %s", path)
+	}()
+}
+
+// copy what's been drawn to the framebuffer so far into a screenshot
+func (game *Game) captureScreenshot() {
+	game.ScreenshotPending = false
+	width, height := game.Framebuffer.Bounds().Dx(), game.Framebuffer.Bounds().Dy()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	game.Framebuffer.ReadPixels(img.Pix)
+	game.saveScreenshot(img, game.World.Seed, game.Settings.ScreenshotScale, "")
+}
+
+// draw every loaded chunk into a poster, in the background.
+// the world is copied first, the sync goroutine and the game loop keep changing it while the poster is drawn.
+func (game *Game) takePoster() {
+	world := game.World.snapshot()
+	direction, seed := game.Direction, game.World.Seed
+
+	game.notify("Drawing poster of %d chunks...", len(world.Chunks))
+	go func() {
+		textures, err := loadOfflineTextures(&defaultVoxelDictionary, "assets/block_atlas.png")
+		if err != nil {
+			game.notify("Failed to load block textures: %v", err)
+			return
+		}
+		img, err := world.renderPoster(direction, textures)
+		if err != nil {
+			game.notify("Failed to draw poster: %v", err)
+			return
+		}
+		// posters are already as big as the world is drawn, so they aren't scaled
+		game.saveScreenshot(img, seed, 1, "poster")
+	}()
+}
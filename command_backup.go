@@ -0,0 +1,77 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"strconv"
+)
+
+// BACKUP COMMAND
+// manage world backups without starting the game, e.g. to get a world back that won't load any more.
+
+// isometrica backup list --save save/demo
+// isometrica backup restore --save save/demo --snapshot 2
+func backupCommand(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: isometrica backup create|list|restore|prune --save <world> [--snapshot <number or file>]")
+	}
+	action := args[0]
+
+	flags := flag.NewFlagSet("backup "+action, flag.ContinueOnError)
+	savePath := flags.String("save", "", "save directory of the world")
+	snapshot := flags.String("snapshot", "1", "backup to restore, a number from the list (1 is the newest) or a file")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	if *savePath == "" {
+		return fmt.Errorf("--save is required")
+	}
+
+	switch action {
+	case "create":
+		backupPath, err := createBackup(*savePath, "manual")
+		if err != nil {
+			return err
+		}
+		log.Printf("Backed up %s to %s", *savePath, backupPath)
+
+	case "list":
+		backups, err := listBackups(*savePath)
+		if err != nil {
+			return err
+		}
+		if len(backups) == 0 {
+			fmt.Printf("no backups of %s\n", *savePath)
+		}
+		for i, backup := range backups {
+			fmt.Printf("%3d  %s  %-24s %8d KB\n", i+1, backup.Time.Format("2006-01-02 15:04:05"), backup.Reason, backup.Size/1024)
+		}
+
+	case "restore":
+		backupPath := *snapshot
+		if number, err := strconv.Atoi(*snapshot); err == nil {
+			backups, err := listBackups(*savePath)
+			if err != nil {
+				return err
+			}
+			if number < 1 || number > len(backups) {
+				return fmt.Errorf("there is no backup %d, %s has %d", number, *savePath, len(backups))
+			}
+			backupPath = backups[number-1].Path
+		}
+		if err := restoreBackup(*savePath, backupPath); err != nil {
+			return err
+		}
+		log.Printf("Restored %s from %s", *savePath, backupPath)
+
+	case "prune":
+		if err := pruneBackups(*savePath, backupPolicy); err != nil {
+			return err
+		}
+
+	default:
+		return fmt.Errorf("unknown backup action %q", action)
+	}
+	return nil
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"log"
+	"os"
+	"time"
+)
+
+// MAP COMMAND
+// generates chunks around the origin without a display and writes a top-down map of them
+
+// colors for the biome map mode
+var biomeMapColors = map[Biome]color.RGBA{
+	BiomePlains:   {120, 190, 80, 255},
+	BiomeForest:   {40, 120, 50, 255},
+	BiomeDesert:   {230, 210, 130, 255},
+	BiomeSnowy:    {235, 240, 250, 255},
+	BiomeMountain: {130, 130, 140, 255},
+}
+
+// get the average color of every voxel texture in a dictionary, read straight from the atlas file.
+// this doesn't need a running game, unlike reading pixels from an ebiten image.
+func loadVoxelMapColors(vDict *VoxelDictionary, atlasPath string) (colors map[string]color.RGBA, err error) {
+	file, err := os.Open(atlasPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	atlas, err := png.Decode(file)
+	if err != nil {
+		return nil, err
+	}
+
+	colors = make(map[string]color.RGBA)
+	for _, voxel := range vDict.Voxels {
+		var r, g, b, count uint64
+		for y := voxel.TextureRect.Min.Y; y < voxel.TextureRect.Max.Y; y++ {
+			for x := voxel.TextureRect.Min.X; x < voxel.TextureRect.Max.X; x++ {
+				pr, pg, pb, pa := atlas.At(x, y).RGBA()
+				if pa < 0x8000 {
+					continue
+				}
+				r, g, b = r+uint64(pr>>8), g+uint64(pg>>8), b+uint64(pb>>8)
+				count++
+			}
+		}
+		if count > 0 {
+			colors[voxel.Name] = color.RGBA{uint8(r / count), uint8(g / count), uint8(b / count), 255}
+		}
+	}
+	return colors, nil
+}
+
+// multiply a color by a brightness factor
+func shadeColor(c color.RGBA, factor float64) color.RGBA {
+	shade := func(v uint8) uint8 {
+		return uint8(max(0, min(255, float64(v)*factor)))
+	}
+	return color.RGBA{shade(c.R), shade(c.G), shade(c.B), c.A}
+}
+
+// get the topmost non-air voxel in a chunk column
+func (chunk *Chunk) topVoxel(x, y int) (voxel Voxel, z int) {
+	for z = chunk.Depth - 1; z >= 0; z-- {
+		voxel = chunk.GetVoxel(x, y, z)
+		if voxel.Name != "Air" {
+			return voxel, z
+		}
+	}
+	return voxel, -1
+}
+
+// get the color of a chunk column for a map.
+// mode is one of "blocks", "height" or "biome".
+func (world *World) mapColumnColor(chunk *Chunk, position [2]int, x, y int, mode string, voxelColors map[string]color.RGBA) color.RGBA {
+	voxel, z := chunk.topVoxel(x, y)
+
+	switch mode {
+	case "height":
+		level := uint8(clampi(z*255/max(1, chunk.Depth-1), 0, 255))
+		if voxel.Name == "Water" {
+			return color.RGBA{0, 0, level, 255}
+		}
+		return color.RGBA{level, level, level, 255}
+	case "biome":
+		if voxel.Name == "Water" {
+			return color.RGBA{40, 70, 160, 255}
+		}
+		return biomeMapColors[*getBiome(position[0], position[1], x, y)]
+	}
+
+	base, ok := voxelColors[voxel.Name]
+	if !ok {
+		base = color.RGBA{255, 0, 255, 255}
+	}
+
+	// water gets darker the deeper it is
+	if voxel.Name == "Water" {
+		floorZ := z
+		for floorZ > 0 && chunk.GetVoxel(x, y, floorZ).Name == "Water" {
+			floorZ--
+		}
+		return shadeColor(base, 1-float64(min(z-floorZ, 12))/20)
+	}
+
+	// simple hill shading, lit from the north west
+	_, neighborZ := chunk.topVoxel(max(0, x-1), max(0, y-1))
+	factor := 1 + float64(z-world.WaterLevel)/60
+	if z > neighborZ {
+		factor += .12
+	} else if z < neighborZ {
+		factor -= .12
+	}
+	return shadeColor(base, factor)
+}
+
+// render a top-down map of the chunks within radius of the origin
+func (world *World) renderMap(radius int, scale int, mode string, voxelColors map[string]color.RGBA) *image.RGBA {
+	size := (radius*2 + 1) * world.ChunkSize
+	img := image.NewRGBA(image.Rect(0, 0, size*scale, size*scale))
+
+	for chunkX := -radius; chunkX <= radius; chunkX++ {
+		for chunkY := -radius; chunkY <= radius; chunkY++ {
+			position := [2]int{chunkX, chunkY}
+			world.generateChunk(position, world.ChunkSize, world.ChunkSize, world.ChunkDepth, defaultVoxelDictionary)
+			chunk := world.Chunks[position]
+
+			for x := 0; x < chunk.Width; x++ {
+				for y := 0; y < chunk.Height; y++ {
+					c := world.mapColumnColor(&chunk, position, x, y, mode, voxelColors)
+					pixelX := ((chunkX+radius)*world.ChunkSize + x) * scale
+					pixelY := ((chunkY+radius)*world.ChunkSize + y) * scale
+					for sx := 0; sx < scale; sx++ {
+						for sy := 0; sy < scale; sy++ {
+							img.SetRGBA(pixelX+sx, pixelY+sy, c)
+						}
+					}
+				}
+			}
+
+			// drop the chunk again so big maps don't eat all the memory
+			delete(world.Chunks, position)
+		}
+	}
+
+	return img
+}
+
+// write an image to a PNG file
+func writePNG(path string, img image.Image) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err = png.Encode(file, img); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// isometrica map --seed N --radius R --out map.png
+func mapCommand(args []string) error {
+	flags := flag.NewFlagSet("map", flag.ContinueOnError)
+	seed := flags.Int64("seed", 0, "world seed")
+	radius := flags.Int("radius", 4, "radius in chunks around the origin")
+	out := flags.String("out", "map.png", "output PNG path")
+	scale := flags.Int("scale", 1, "pixels per voxel")
+	mode := flags.String("mode", "blocks", "map mode: blocks, height or biome")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *radius < 0 || *scale < 1 {
+		return fmt.Errorf("radius must be >= 0 and scale must be >= 1")
+	}
+	if *mode != "blocks" && *mode != "height" && *mode != "biome" {
+		return fmt.Errorf("unknown map mode %q", *mode)
+	}
+
+	voxelColors, err := loadVoxelMapColors(&defaultVoxelDictionary, "assets/block_atlas.png")
+	if err != nil {
+		return fmt.Errorf("failed to load block colors: %v", err)
+	}
+
+	start := time.Now()
+	world := World{}
+	world.Initialize(*seed)
+	img := world.renderMap(*radius, *scale, *mode, voxelColors)
+
+	if err := writePNG(*out, img); err != nil {
+		return err
+	}
+	log.Printf("Wrote %dx%d map of seed %d to %s in %v", img.Bounds().Dx(), img.Bounds().Dy(), *seed, *out, time.Since(start).Round(time.Millisecond))
+	return nil
+}
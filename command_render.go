@@ -0,0 +1,208 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"log"
+	"os"
+	"slices"
+	"time"
+)
+
+// RENDER COMMAND
+// draws an area of a world the same way Chunk.Render does, but onto a plain image in memory,
+// so it works without a window and the output can be as big as we want.
+
+// voxel textures cut out of the atlas file, for drawing without ebiten
+type offlineTextures map[string]image.Image
+
+// load the textures of every voxel in a dictionary straight from the atlas file
+func loadOfflineTextures(vDict *VoxelDictionary, atlasPath string) (textures offlineTextures, err error) {
+	file, err := os.Open(atlasPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	decoded, err := png.Decode(file)
+	if err != nil {
+		return nil, err
+	}
+
+	// convert so we can cut sub images out of it
+	atlas := image.NewNRGBA(decoded.Bounds())
+	draw.Draw(atlas, atlas.Bounds(), decoded, image.Point{}, draw.Src)
+
+	textures = make(offlineTextures)
+	for _, voxel := range vDict.Voxels {
+		textures[voxel.Name] = atlas.SubImage(voxel.TextureRect)
+	}
+	return textures, nil
+}
+
+// get a voxel by global position from the loaded chunks, "Air" if the chunk isn't loaded
+func (world *World) getLoadedVoxelName(x, y, z int) string {
+	chunkX, chunkY := floorDiv(x, world.ChunkSize), floorDiv(y, world.ChunkSize)
+	chunk, exists := world.Chunks[[2]int{chunkX, chunkY}]
+	if !exists || z < 0 || z >= chunk.Depth {
+		return "Air"
+	}
+	return chunk.GetVoxel(x-chunkX*world.ChunkSize, y-chunkY*world.ChunkSize, z).Name
+}
+
+// render the loaded chunks between two chunk positions (inclusive) into an image.
+// uses the same draw order and culling as Chunk.Render, just across the whole area at once.
+func (world *World) renderIsometric(minChunk, maxChunk [2]int, direction [4]int, textures offlineTextures) *image.RGBA {
+	minX, minY := minChunk[0]*world.ChunkSize, minChunk[1]*world.ChunkSize
+	maxX, maxY := (maxChunk[0]+1)*world.ChunkSize, (maxChunk[1]+1)*world.ChunkSize
+	depth := world.ChunkDepth
+
+	// find the screen space bounds by projecting the corners of the area
+	screenMinX, screenMinY := 1<<30, 1<<30
+	screenMaxX, screenMaxY := -1<<30, -1<<30
+	for _, corner := range [][3]int{{minX, minY, 0}, {maxX, minY, 0}, {minX, maxY, 0}, {maxX, maxY, 0}, {minX, minY, depth}, {maxX, minY, depth}, {minX, maxY, depth}, {maxX, maxY, depth}} {
+		screenX, screenY := getScreenPosition(corner[0], corner[1], corner[2], 0, 0, 0, direction)
+		screenMinX, screenMinY = min(screenMinX, screenX), min(screenMinY, screenY)
+		screenMaxX, screenMaxY = max(screenMaxX, screenX), max(screenMaxY, screenY)
+	}
+	img := image.NewRGBA(image.Rect(0, 0, screenMaxX-screenMinX+tileWidth, screenMaxY-screenMinY+tileHeight))
+	cameraX, cameraY := float32(-screenMinX), float32(-screenMinY)
+
+	transparentNames := defaultVoxelDictionary.GetTransparentNames()
+	isTransparent := func(x, y, z int) bool {
+		// the edges of the area are drawn like chunk edges are in game
+		if x < minX || y < minY || x >= maxX || y >= maxY {
+			return true
+		}
+		return slices.Contains(transparentNames, world.getLoadedVoxelName(x, y, z))
+	}
+
+	// keep track of what actually got drawn, so the empty sky can be cropped off
+	drawn := image.Rectangle{}
+
+	stepX, stepY := renderStep(direction)
+	startX, stopX := renderRange(stepX, maxX-minX)
+	startY, stopY := renderRange(stepY, maxY-minY)
+	for lx := startX; lx != stopX; lx += stepX {
+		for ly := startY; ly != stopY; ly += stepY {
+			x, y := minX+lx, minY+ly
+			for z := 0; z < depth; z++ {
+				name := world.getLoadedVoxelName(x, y, z)
+				if name == "Air" {
+					continue
+				}
+
+				// check if the voxel is even visible
+				if !(isTransparent(x+stepX, y, z) || isTransparent(x, y+stepY, z) || isTransparent(x, y, z+1)) {
+					continue
+				}
+
+				// hide any transparent under itself
+				if slices.Contains(transparentNames, name) &&
+					world.getLoadedVoxelName(x+stepX, y, z) == name &&
+					world.getLoadedVoxelName(x, y+stepY, z) == name &&
+					world.getLoadedVoxelName(x, y, z+1) == name {
+					continue
+				}
+
+				texture, ok := textures[name]
+				if !ok {
+					continue
+				}
+				screenX, screenY := getScreenPosition(x, y, z, cameraX, cameraY, 0, direction)
+				tileRect := image.Rect(screenX, screenY, screenX+tileWidth, screenY+tileHeight)
+				draw.Draw(img, tileRect, texture, texture.Bounds().Min, draw.Over)
+				drawn = drawn.Union(tileRect)
+			}
+		}
+	}
+
+	return img.SubImage(drawn).(*image.RGBA)
+}
+
+// fill the world with the chunks in an area, loading them from the save if they exist
+func (world *World) loadArea(minChunk, maxChunk [2]int) error {
+	for x := minChunk[0]; x <= maxChunk[0]; x++ {
+		for y := minChunk[1]; y <= maxChunk[1]; y++ {
+			if world.SavePath != "" && world.chunkExists(x, y) {
+				chunk, err := world.LoadChunk(x, y)
+				if err != nil {
+					return fmt.Errorf("failed to load chunk %d, %d: %v", x, y, err)
+				}
+				world.Chunks[[2]int{x, y}] = chunk
+			} else {
+				world.generateChunk([2]int{x, y}, world.ChunkSize, world.ChunkSize, world.ChunkDepth, defaultVoxelDictionary)
+			}
+		}
+	}
+	return nil
+}
+
+// isometrica render --save save/demo --radius 2 --direction north --out render.png
+func renderCommand(args []string) error {
+	flags := flag.NewFlagSet("render", flag.ContinueOnError)
+	savePath := flags.String("save", "", "save directory to render, generates from --seed if empty")
+	seed := flags.Int64("seed", 0, "world seed, when not rendering a save")
+	centerX := flags.Int("x", 0, "chunk x to center on (defaults to the player's chunk for saves)")
+	centerY := flags.Int("y", 0, "chunk y to center on (defaults to the player's chunk for saves)")
+	radius := flags.Int("radius", 1, "radius in chunks around the center")
+	directionFlag := flags.String("direction", "south", "camera direction: south, west, north or east")
+	out := flags.String("out", "render.png", "output PNG path")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *radius < 0 {
+		return fmt.Errorf("radius must be >= 0")
+	}
+	direction, ok := directionFromName(*directionFlag)
+	if !ok {
+		return fmt.Errorf("unknown direction %q", *directionFlag)
+	}
+
+	// work out which flags were given, so saves can default to the player position
+	given := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) { given[f.Name] = true })
+
+	textures, err := loadOfflineTextures(&defaultVoxelDictionary, "assets/block_atlas.png")
+	if err != nil {
+		return fmt.Errorf("failed to load block textures: %v", err)
+	}
+
+	start := time.Now()
+	game := &Game{}
+	if *savePath != "" {
+		// loading can migrate and back up the save, so it can't be open in the game at the same time
+		lock, err := acquireSaveLock(*savePath)
+		if err != nil {
+			return err
+		}
+		defer lock.Release()
+
+		if err := game.LoadGame(*savePath); err != nil {
+			return fmt.Errorf("failed to load save: %v", err)
+		}
+		if !given["x"] {
+			*centerX = floorDiv(int(game.Player.Position.X), game.World.ChunkSize)
+		}
+		if !given["y"] {
+			*centerY = floorDiv(int(game.Player.Position.Y), game.World.ChunkSize)
+		}
+	} else {
+		game.World.Initialize(*seed)
+	}
+
+	minChunk := [2]int{*centerX - *radius, *centerY - *radius}
+	maxChunk := [2]int{*centerX + *radius, *centerY + *radius}
+	if err := game.World.loadArea(minChunk, maxChunk); err != nil {
+		return err
+	}
+	img := game.World.renderIsometric(minChunk, maxChunk, direction, textures)
+
+	if err := writePNG(*out, img); err != nil {
+		return err
+	}
+	log.Printf("Wrote %dx%d render of seed %d to %s in %v", img.Bounds().Dx(), img.Bounds().Dy(), game.World.Seed, *out, time.Since(start).Round(time.Millisecond))
+	return nil
+}
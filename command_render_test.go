@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"image"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "write the golden images instead of comparing against them")
+
+// read a png into RGBA so it can be compared byte for byte
+func readRGBA(t *testing.T, path string) *image.RGBA {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	decoded, err := png.Decode(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img := image.NewRGBA(image.Rect(0, 0, decoded.Bounds().Dx(), decoded.Bounds().Dy()))
+	draw.Draw(img, img.Bounds(), decoded, decoded.Bounds().Min, draw.Src)
+	return img
+}
+
+func TestRenderCommandGolden(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "render.png")
+	if err := renderCommand([]string{"--seed", "42", "--x", "0", "--y", "0", "--radius", "0", "--direction", "north", "--out", out}); err != nil {
+		t.Fatal(err)
+	}
+
+	golden := filepath.Join("testdata", "render", "seed42-north.png")
+	if *updateGolden {
+		data, err := os.ReadFile(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.MkdirAll(filepath.Dir(golden), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(golden, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, expected := readRGBA(t, out), readRGBA(t, golden)
+	if got.Bounds() != expected.Bounds() {
+		t.Fatalf("render is %v, expected %v", got.Bounds(), expected.Bounds())
+	}
+	if !bytes.Equal(got.Pix, expected.Pix) {
+		t.Errorf("render doesn't match %s, run the tests with -update if the change is on purpose", golden)
+	}
+}
+
+func TestRenderCommandRefusesOpenWorld(t *testing.T) {
+	savePath := copyFixtureSave(t, "v0")
+	lock, err := acquireSaveLock(savePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lock.Release()
+
+	if err := renderCommand([]string{"--save", savePath, "--radius", "0", "--out", filepath.Join(t.TempDir(), "render.png")}); err == nil {
+		t.Fatal("rendered a world that is open somewhere else")
+	}
+	// and didn't migrate it out from under the game
+	if version, _ := readSaveFormatVersion(savePath); version != 0 {
+		t.Errorf("save was migrated to version %d", version)
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+)
+
+// COMMANDS
+// isometrica can be started with a subcommand to do things without opening the game window,
+// e.g. `isometrica map --seed 42 --radius 4 --out map.png`
+
+// a subcommand, gets the arguments after its name
+type Command struct {
+	Description string
+	Run         func(args []string) error
+}
+
+var commands = map[string]Command{
+	"backup": {Description: "create, list, restore or prune backups of a world", Run: backupCommand},
+	"map":    {Description: "render a top-down map of a seed to a PNG", Run: mapCommand},
+	"render": {Description: "render an isometric view of a save or seed to a PNG", Run: renderCommand},
+}
+
+// run a subcommand by name
+func runCommand(name string, args []string) error {
+	command, ok := commands[name]
+	if !ok {
+		printCommandUsage()
+		return fmt.Errorf("unknown command %q", name)
+	}
+	return command.Run(args)
+}
+
+// print the list of subcommands
+func printCommandUsage() {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(os.Stderr, "usage: isometrica [command] [flags]")
+	fmt.Fprintln(os.Stderr, "run without a command to start the game. commands:")
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", name, commands[name].Description)
+	}
+}
@@ -37,8 +37,18 @@ func (game *Game) Draw(screen *ebiten.Image) {
 		_ = gameStateDrawRun(game, screen)
 	case GAMESTATE_TITLE:
 		_ = gameStateDrawTitle(game, screen)
+	case GAMESTATE_WORLDS:
+		_ = gameStateDrawWorlds(game, screen)
 	case GAMESTATE_MENU:
 		_ = gameStateDrawMenu(game, screen)
+	case GAMESTATE_SETTINGS:
+		_ = gameStateDrawSettings(game, screen)
+	case GAMESTATE_CONTROLS:
+		_ = gameStateDrawControls(game, screen)
+	case GAMESTATE_MAP:
+		_ = gameStateDrawMap(game, screen)
+	case GAMESTATE_WAYPOINTS:
+		_ = gameStateDrawWaypoints(game, screen)
 	}
 
 }
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"image/color"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// how many actions fit on the controls screen at once
+const controlsVisible = 12
+
+// show a binding the way the controls screen lists it
+func describeBinding(binding Binding) string {
+	var names []string
+	names = append(names, binding.Keys...)
+	for _, name := range binding.Mouse {
+		names = append(names, "Mouse "+name)
+	}
+	for _, name := range binding.Gamepad {
+		names = append(names, "Pad "+name)
+	}
+	if len(names) == 0 {
+		return "-"
+	}
+	return strings.Join(names, ", ")
+}
+
+func gameStateDrawControls(game *Game, screen *ebiten.Image) error {
+	game.Framebuffer.Fill(color.RGBA{0, 0, 88, 255})
+
+	game.drawString(game.Framebuffer, "CONTROLS", 100, 40, true)
+
+	// scroll so the selection stays on screen
+	first := game.ControlSelection - controlsVisible/2
+	first = max(0, min(first, len(actions)-controlsVisible))
+	for i := first; i < len(actions) && i < first+controlsVisible; i++ {
+		info := actions[i]
+		prefix := "  "
+		if i == game.ControlSelection {
+			prefix = "> "
+		}
+		value := describeBinding(game.Settings.Bindings[info.Action])
+		if i == game.ControlSelection && game.Rebinding {
+			value = "press a key or button..."
+		}
+		y := 65 + (i-first)*15
+		game.drawString(game.Framebuffer, prefix+info.Label, 60, y, true)
+		game.drawString(game.Framebuffer, value, 240, y, true)
+	}
+
+	help := "ENTER rebind  DEL clear  R reset  ESC back"
+	if gamepadConnected() {
+		help = "A rebind  X clear  Y reset  B back"
+	}
+	if game.Rebinding {
+		help = "ESC cancel"
+	}
+	game.drawString(game.Framebuffer, help, 60, 65+controlsVisible*15+15, true)
+
+	screen.DrawImage(game.Framebuffer, nil)
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// the minimap is this many pixels across, one pixel per voxel
+const minimapSize = 80
+
+// draw an arrow at x, y pointing the way the camera faces, up the screen in the game
+func drawHeadingMarker(screen *ebiten.Image, x, y float32, direction [4]int, size float32) {
+	heading := screenToWorldMovement(direction, 0, -1)
+	forwardX, forwardY := heading.X*size, heading.Y*size
+	sideX, sideY := -forwardY/2, forwardX/2
+
+	tipX, tipY := x+forwardX, y+forwardY
+	leftX, leftY := x-forwardX/2+sideX, y-forwardY/2+sideY
+	rightX, rightY := x-forwardX/2-sideX, y-forwardY/2-sideY
+	for _, line := range [][4]float32{{tipX, tipY, leftX, leftY}, {leftX, leftY, x, y}, {x, y, rightX, rightY}, {rightX, rightY, tipX, tipY}} {
+		vector.StrokeLine(screen, line[0], line[1], line[2], line[3], 1, color.RGBA{255, 60, 60, 255}, false)
+	}
+	vector.DrawFilledCircle(screen, x, y, 1.5, color.White, false)
+}
+
+// draw the minimap in the top right corner, around the player
+func (game *Game) drawMinimap(screen *ebiten.Image) {
+	if game.Minimap == nil {
+		game.Minimap = ebiten.NewImage(minimapSize, minimapSize)
+	}
+	pixels := make([]byte, minimapSize*minimapSize*4)
+	game.Map.Paint(pixels, minimapSize, minimapSize, float64(game.Player.Position.X), float64(game.Player.Position.Y), 1)
+	game.Minimap.WritePixels(pixels)
+
+	left, top := float32(game.ScreenX-minimapSize-4), float32(4)
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Translate(float64(left), float64(top))
+	screen.DrawImage(game.Minimap, op)
+	vector.StrokeRect(screen, left-.5, top-.5, minimapSize+1, minimapSize+1, 1, color.RGBA{255, 255, 255, 160}, false)
+	game.drawWaypointsOnMap(screen, left, top, minimapSize, minimapSize, float64(game.Player.Position.X), float64(game.Player.Position.Y), 1, false)
+	drawHeadingMarker(screen, left+minimapSize/2, top+minimapSize/2, game.Direction, 6)
+}
+
+func gameStateDrawMap(game *Game, screen *ebiten.Image) error {
+	width, height := game.ScreenX, game.ScreenY
+	if game.MapScale == 0 {
+		game.MapScale = 1
+	}
+
+	// only paint the map again when it has been moved or explored, it's a lot of pixels
+	painted := [6]float64{game.MapCenter[0], game.MapCenter[1], game.MapScale, float64(width), float64(height), float64(game.Map.GetVersion())}
+	if game.MapImage == nil || game.MapImage.Bounds().Dx() != width || game.MapImage.Bounds().Dy() != height {
+		game.MapImage = ebiten.NewImage(width, height)
+		game.MapPainted = [6]float64{}
+	}
+	if painted != game.MapPainted {
+		pixels := make([]byte, width*height*4)
+		game.Map.Paint(pixels, width, height, game.MapCenter[0], game.MapCenter[1], game.MapScale)
+		game.MapImage.WritePixels(pixels)
+		game.MapPainted = painted
+	}
+	game.Framebuffer.DrawImage(game.MapImage, nil)
+
+	game.drawWaypointsOnMap(game.Framebuffer, 0, 0, width, height, game.MapCenter[0], game.MapCenter[1], game.MapScale, true)
+
+	// the player, pointing the way the camera faces
+	playerX, playerY := mapScreenPosition(float64(game.Player.Position.X), float64(game.Player.Position.Y), game.MapCenter[0], game.MapCenter[1], game.MapScale, width, height)
+	drawHeadingMarker(game.Framebuffer, float32(playerX), float32(playerY), game.Direction, 8)
+
+	game.drawString(game.Framebuffer, "MAP", 4, 4, true)
+	game.drawString(game.Framebuffer, fmt.Sprintf("%.0f, %.0f  %gx", game.MapCenter[0], game.MapCenter[1], game.MapScale), 4, 16, true)
+	help := "Move or drag to pan, +/- to zoom, Enter to find yourself, M to close"
+	if gamepadConnected() {
+		help = "Stick to pan, stick buttons to zoom, A to find yourself, B to close"
+	}
+	game.drawString(game.Framebuffer, help, 4, height-12, true)
+
+	screen.DrawImage(game.Framebuffer, nil)
+	return nil
+}
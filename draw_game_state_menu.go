@@ -11,7 +11,13 @@ func gameStateDrawMenu(game *Game, screen *ebiten.Image) error {
 
 	game.drawString(game.Framebuffer, "ISOMETRICA", 100, 100, true)
 	game.drawString(game.Framebuffer, "GAME IS PAUSED", 100, 115, true)
-	game.drawString(game.Framebuffer, "Press Enter", 100, 130, true)
+	for i, item := range pauseMenuItems {
+		prefix := "  "
+		if i == game.PauseSelection {
+			prefix = "> "
+		}
+		game.drawString(game.Framebuffer, prefix+item, 100, 140+i*15, true)
+	}
 
 	screen.DrawImage(game.Framebuffer, nil)
 
@@ -7,57 +7,69 @@ import (
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
 )
 
 func gameStateDrawRun(game *Game, screen *ebiten.Image) error {
 	// fill background
 	game.Framebuffer.Fill(color.RGBA{0, 0, 88, 255})
 
+	// the sync goroutine can't load or unload chunks while they're being drawn
+	game.World.readLockChunks()
+	defer game.World.readUnlockChunks()
+
 	// render the chunks
 
-	// get proper render order based on camera direction
-	startX, stopX, stepX := 0, 0, 0
-	startY, stopY, stepY := 0, 0, 0
-	switch game.Direction {
-	case SOUTH:
-		startX, stopX, stepX = -2, 2, 1
-		startY, stopY, stepY = -2, 2, 1
-	case WEST:
-		startX, stopX, stepX = -2, 2, 1
-		startY, stopY, stepY = 2, -2, -1
-	case NORTH:
-		startX, stopX, stepX = 2, -2, -1
-		startY, stopY, stepY = 2, -2, -1
-	case EAST:
-		startX, stopX, stepX = -2, 2, 1
-		startY, stopY, stepY = 2, -2, -1
+	// the chunks on screen, already in the order they have to be drawn in
+	originX, originY := game.renderOrigin()
+	var blocksRendered int
+	playerDrawn := false
+	for _, key := range game.VisibleChunks {
+		chunk, exists := game.World.Chunks[key]
+		if !exists {
+			continue
+		}
+		// get screen position of the top voxel in the chunk
+		screenX, screenY := getScreenPosition(
+			key[0]*game.World.ChunkSize,
+			key[1]*game.World.ChunkSize,
+			0,
+			originX,
+			originY,
+			game.DepthShift,
+			game.Direction,
+		)
+		// render
+		// the player is drawn in with the chunk they're standing in
+		playerChunk := key == game.CurrentChunk
+		playerDrawn = playerDrawn || playerChunk
+		blocksRendered += chunk.Render(game.Framebuffer, float32(screenX), float32(screenY), game.DepthShift, game, playerChunk)
 	}
 
-	// FIXME: fix the directional camera addition for chunk displcement
-	var blocksRendered int
-	for x := startX; x <= stopX; x += stepX {
-		for y := startY; y <= stopY; y += stepY {
-			// check if chunk is on screen
-			if !ChunkContainingGlobalPointVisibleInViewport((game.CurrentChunk[0]+x)*v, (game.CurrentChunk[1]+y)*v, 0, game.Camera[0]-float32(v*v/2), game.Camera[1]-float32(v*v/4), game.DepthShift, game.ScreenX, game.ScreenY, game.Direction) {
-				continue
-			}
+	// the player was drawn in with the world, so show where they are when the world is in front of them.
+	// if their chunk isn't loaded yet there's nothing to be in front of them.
+	if !playerDrawn {
+		game.Player.Render(game.Framebuffer, originX, originY, game.Direction, false)
+	} else if game.Player.Hidden(&game.World, game.Direction, game.cutawayTop()) {
+		game.Player.Render(game.Framebuffer, originX, originY, game.Direction, true)
+	}
 
-			chunk, exists := game.World.Chunks[[2]int{x + game.CurrentChunk[0], y + game.CurrentChunk[1]}]
-			if exists {
-				// get screen position of the top voxel in the chunk
-				screenX, screenY := getScreenPosition(
-					(x+game.CurrentChunk[0])*game.World.ChunkSize,
-					(y+game.CurrentChunk[1])*game.World.ChunkSize,
-					0,
-					game.Camera[0],
-					game.Camera[1],
-					game.DepthShift,
-					game.Direction,
-				)
-				// render
-				blocksRendered += chunk.Render(game.Framebuffer, float32(screenX-(v*v/2)), float32(screenY-(v*v/4)), game.DepthShift, game, true)
-			}
-		}
+	// screenshots are the world without anything drawn over it
+	if game.ScreenshotPending {
+		game.captureScreenshot()
+	}
+
+	// outline the top of the voxel under the cursor
+	if game.HasTarget {
+		targetX, targetY := getScreenPosition(game.Target[0], game.Target[1], game.Target[2], originX, originY, game.DepthShift, game.Direction)
+		drawTileOutline(game.Framebuffer, float32(targetX), float32(targetY), color.RGBA{255, 255, 255, 200})
+	}
+
+	// the gamepad cursor has nothing drawing it, so draw a crosshair
+	if game.GamepadCursor {
+		x, y := float32(game.Cursor[0]), float32(game.Cursor[1])
+		vector.StrokeLine(game.Framebuffer, x-4, y, x+4, y, 1, color.White, false)
+		vector.StrokeLine(game.Framebuffer, x, y-4, x, y+4, 1, color.White, false)
 	}
 
 	if game.DebugMode {
@@ -95,25 +107,22 @@ func gameStateDrawRun(game *Game, screen *ebiten.Image) error {
 		)
 	}
 
-	// draw the player
-	game.Player.Render(game.Framebuffer, game.DepthShift)
-
 	// gui/text
 
-	// get the name of the camera direction
-	var cameraDirection string
-	switch game.Direction {
-	case SOUTH:
-		cameraDirection = "South"
-	case WEST:
-		cameraDirection = "West"
-	case NORTH:
-		cameraDirection = "North"
-	case EAST:
-		cameraDirection = "East"
+	game.drawWaypointMarkers(game.Framebuffer, originX, originY)
+	if game.Settings.Minimap && game.Map != nil {
+		game.drawMinimap(game.Framebuffer)
 	}
-
 	game.drawString(game.Framebuffer, "ISOMETRICA Infdev", int(0), int(0), true)
+	if game.Cutaway {
+		game.drawString(game.Framebuffer, fmt.Sprintf("Cut-Away above z %d", game.cutawayTop()), 0, game.ScreenY-12, true)
+	}
+	if game.Teleport != nil {
+		game.drawString(game.Framebuffer, "Teleporting...", 0, game.ScreenY-24, true)
+	}
+	if game.Notice != "" && time.Since(game.NoticeTime) < noticeDuration {
+		game.drawString(game.Framebuffer, game.Notice, 0, game.ScreenY-36, true)
+	}
 	if game.DebugMode {
 		game.drawString(game.Framebuffer, fmt.Sprintf("Player Position: %f, %f, %f", game.Player.Position.X, game.Player.Position.Y, game.Player.Position.Z), 0, 22, true)
 		game.drawString(game.Framebuffer, fmt.Sprintf("Local position: %f, %f, %f", game.Player.Position.X-float32(game.CurrentChunk[0]*game.World.ChunkSize), game.Player.Position.Y-float32(game.CurrentChunk[1]*game.World.ChunkSize), game.Player.Position.Z), 0, 34, true)
@@ -122,7 +131,10 @@ func gameStateDrawRun(game *Game, screen *ebiten.Image) error {
 		game.drawString(game.Framebuffer, fmt.Sprintf("Blocks Rendered: %d", blocksRendered), 0, 70, true)
 		game.drawString(game.Framebuffer, fmt.Sprintf("Chunks Loaded: %d, World Byte Size: %d", len(game.World.Chunks), MapSize(game.World.Chunks)), 0, 82, true)
 		game.drawString(game.Framebuffer, fmt.Sprintf("Velocity: %f, %f, %f", game.Player.Velocity.X, game.Player.Velocity.Y, game.Player.Velocity.Z), 0, 94, true)
-		game.drawString(game.Framebuffer, fmt.Sprintf("Camera rotation: %s, %v", cameraDirection, game.Direction), 0, 106, true)
+		game.drawString(game.Framebuffer, fmt.Sprintf("Camera rotation: %s, %v", directionName(game.Direction), game.Direction), 0, 106, true)
+		if game.XRayMode {
+			game.drawString(game.Framebuffer, "X-Ray (F4)", 0, 118, true)
+		}
 	} else {
 		// drawString(game.Framebuffer, fmt.Sprintf("%f, %f, %f", game.Player.Position.X, game.Player.Position.Y, game.Player.Position.Z), 0, 22, true)
 	}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+func gameStateDrawSettings(game *Game, screen *ebiten.Image) error {
+	game.Framebuffer.Fill(color.RGBA{0, 0, 88, 255})
+
+	game.drawString(game.Framebuffer, "SETTINGS", 100, 100, true)
+	for i, item := range settingItems {
+		prefix := "  "
+		if i == game.SettingSelection {
+			prefix = "> "
+		}
+		game.drawString(game.Framebuffer, prefix+item.Name, 100, 125+i*15, true)
+		game.drawString(game.Framebuffer, "< "+item.Value(&game.Settings)+" >", 220, 125+i*15, true)
+	}
+	for i, line := range []string{"Controls", "Back"} {
+		prefix := "  "
+		if game.SettingSelection == len(settingItems)+i {
+			prefix = "> "
+		}
+		game.drawString(game.Framebuffer, prefix+line, 100, 125+(len(settingItems)+i)*15+10, true)
+	}
+
+	screen.DrawImage(game.Framebuffer, nil)
+
+	return nil
+}
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"fmt"
 	"image/color"
 
 	"github.com/hajimehoshi/ebiten/v2"
@@ -13,7 +12,6 @@ func gameStateDrawTitle(game *Game, screen *ebiten.Image) error {
 	game.drawString(game.Framebuffer, "ISOMETRICA", 100, 100, true)
 	game.drawString(game.Framebuffer, "COPYRIGHT MMXXV SOYPACKET", 100, 115, true)
 	game.drawString(game.Framebuffer, "Press Any Key!", 100, 145, true)
-	game.drawString(game.Framebuffer, fmt.Sprintf("%d", game.World.Seed), 100, 160, true)
 
 	screen.DrawImage(game.Framebuffer, nil)
 
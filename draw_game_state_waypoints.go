@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// how many waypoints fit in the waypoints menu at once
+const waypointsVisible = 12
+
+// the color waypoints are marked in
+var waypointColor = color.RGBA{255, 210, 60, 255}
+
+// a waypoint's name and how far away it is
+func (game *Game) waypointLabel(waypoint Waypoint) string {
+	return fmt.Sprintf("%s %.0fm", waypoint.Name, waypoint.Distance(game.Player.Position))
+}
+
+// mark every waypoint in the world, with voxel 0, 0, 0 drawn at originX, originY.
+// waypoints off the screen are kept at its edge, so there's always something to walk towards.
+func (game *Game) drawWaypointMarkers(screen *ebiten.Image, originX, originY float32) {
+	const margin, poleHeight = 4, 24
+	for _, waypoint := range game.Waypoints {
+		feetX, feetY := worldToScreen(float64(waypoint.Position[0]), float64(waypoint.Position[1]), float64(waypoint.Position[2]), game.Direction)
+		x, y := float32(feetX)+originX, float32(feetY)+originY
+
+		label := game.waypointLabel(waypoint)
+		labelWidth := game.Font.stringWidth(label)
+		onScreen := x >= 0 && x < float32(game.ScreenX) && y-poleHeight >= 0 && y < float32(game.ScreenY)
+		if onScreen {
+			// a flag on a pole, standing on the waypoint
+			vector.StrokeLine(screen, x, y, x, y-poleHeight, 1, waypointColor, false)
+			vector.DrawFilledRect(screen, x, y-poleHeight, 7, 5, waypointColor, false)
+			vector.DrawFilledCircle(screen, x, y, 1.5, waypointColor, false)
+		}
+
+		labelX := clampi(int(x)-labelWidth/2, margin, game.ScreenX-labelWidth-margin)
+		labelY := clampi(int(y)-poleHeight-12, margin, game.ScreenY-12-margin)
+		game.drawString(screen, label, labelX, labelY, true)
+	}
+}
+
+// mark every waypoint on a map painted around centerX, centerY
+func (game *Game) drawWaypointsOnMap(screen *ebiten.Image, left, top float32, width, height int, centerX, centerY, scale float64, labels bool) {
+	for _, waypoint := range game.Waypoints {
+		x, y := mapScreenPosition(float64(waypoint.Position[0]), float64(waypoint.Position[1]), centerX, centerY, scale, width, height)
+		if x < 0 || y < 0 || x >= float64(width) || y >= float64(height) {
+			continue
+		}
+		vector.DrawFilledRect(screen, left+float32(x)-1.5, top+float32(y)-1.5, 3, 3, waypointColor, false)
+		if labels {
+			game.drawString(screen, waypoint.Name, int(left)+int(x)+4, int(top)+int(y)-4, true)
+		}
+	}
+}
+
+func gameStateDrawWaypoints(game *Game, screen *ebiten.Image) error {
+	game.Framebuffer.Fill(color.RGBA{0, 0, 88, 255})
+
+	game.drawString(game.Framebuffer, "WAYPOINTS", 100, 40, true)
+
+	// the add line, the waypoints and the back line, scrolled so the selection stays on screen
+	lines := len(game.Waypoints) + 2
+	first := game.WaypointSelection - waypointsVisible/2
+	first = max(0, min(first, lines-waypointsVisible))
+	for i := first; i < lines && i < first+waypointsVisible; i++ {
+		prefix := "  "
+		if i == game.WaypointSelection {
+			prefix = "> "
+		}
+		y := 65 + (i-first)*15
+		switch {
+		case i == 0 && game.WaypointNaming:
+			game.drawString(game.Framebuffer, prefix+"Name: "+game.WaypointName+"_", 60, y, true)
+		case i == 0:
+			game.drawString(game.Framebuffer, prefix+"Add Waypoint Here", 60, y, true)
+		case i == lines-1:
+			game.drawString(game.Framebuffer, prefix+"Back", 60, y, true)
+		default:
+			waypoint := game.Waypoints[i-1]
+			game.drawString(game.Framebuffer, prefix+waypoint.Name, 60, y, true)
+			game.drawString(game.Framebuffer, fmt.Sprintf("%.0f, %.0f, %.0f  %.0fm", waypoint.Position[0], waypoint.Position[1], waypoint.Position[2], waypoint.Distance(game.Player.Position)), 240, y, true)
+		}
+	}
+
+	help := "ENTER teleport  DEL remove  ESC back"
+	if gamepadConnected() {
+		help = "A teleport  X remove  B back"
+	}
+	if game.WaypointNaming {
+		help = "ENTER add  ESC cancel"
+	}
+	game.drawString(game.Framebuffer, help, 60, 65+waypointsVisible*15+15, true)
+	if game.WaypointMessage != "" {
+		game.drawString(game.Framebuffer, game.WaypointMessage, 60, 65+waypointsVisible*15+30, true)
+	}
+
+	screen.DrawImage(game.Framebuffer, nil)
+
+	return nil
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// how far apart rows in the world list are
+const worldListRowHeight = 28
+
+func gameStateDrawWorlds(game *Game, screen *ebiten.Image) error {
+	menu := &game.WorldMenu
+	game.Framebuffer.Fill(color.RGBA{0, 0, 88, 255})
+
+	game.drawString(game.Framebuffer, "WORLDS", 20, 20, true)
+
+	switch menu.Mode {
+	case WORLDMENU_CREATE:
+		nameCursor, seedCursor := "_", ""
+		if menu.EditingSeed {
+			nameCursor, seedCursor = "", "_"
+		}
+		game.drawString(game.Framebuffer, "NEW WORLD", 20, 50, true)
+		game.drawString(game.Framebuffer, "Name: "+menu.NameInput+nameCursor, 20, 70, true)
+		game.drawString(game.Framebuffer, "Seed: "+menu.SeedInput+seedCursor, 20, 85, true)
+		game.drawString(game.Framebuffer, "(leave the seed empty for a random one)", 20, 100, true)
+		game.drawString(game.Framebuffer, "Enter - Create   Tab - Name/Seed   Esc - Cancel", 20, game.ScreenY-40, true)
+
+	case WORLDMENU_RENAME, WORLDMENU_DUPLICATE:
+		title := "RENAME WORLD"
+		if menu.Mode == WORLDMENU_DUPLICATE {
+			title = "DUPLICATE WORLD"
+		}
+		game.drawString(game.Framebuffer, title, 20, 50, true)
+		game.drawString(game.Framebuffer, "Name: "+menu.NameInput+"_", 20, 70, true)
+		game.drawString(game.Framebuffer, "Enter - Confirm   Esc - Cancel", 20, game.ScreenY-40, true)
+
+	case WORLDMENU_DELETE:
+		game.drawString(game.Framebuffer, fmt.Sprintf("Delete %s and its backups for good?", menu.Worlds[menu.Selected].Name), 20, 50, true)
+		game.drawString(game.Framebuffer, "Its backups are deleted too, so it can't be restored afterwards.", 20, 65, true)
+		game.drawString(game.Framebuffer, "Y - Delete   N - Cancel", 20, game.ScreenY-40, true)
+
+	case WORLDMENU_BACKUPS:
+		drawBackupList(game, menu)
+		game.drawString(game.Framebuffer, "Enter - Restore   Esc - Back", 20, game.ScreenY-40, true)
+
+	case WORLDMENU_RESTORE:
+		backup := menu.Backups[menu.SelectedBackup]
+		game.drawString(game.Framebuffer, fmt.Sprintf("Restore %s to how it was at %s?", menu.Worlds[menu.Selected].Name, backup.Time.Format("2006-01-02 15:04:05")), 20, 50, true)
+		game.drawString(game.Framebuffer, "The world is backed up first, so this can be undone.", 20, 65, true)
+		game.drawString(game.Framebuffer, "Y - Restore   N - Cancel", 20, game.ScreenY-40, true)
+
+	default:
+		drawWorldList(game, menu)
+		game.drawString(game.Framebuffer, "Enter - Play   N - New   R - Rename   C - Duplicate   Del - Delete", 20, game.ScreenY-55, true)
+		game.drawString(game.Framebuffer, "B - Back Up   S - Backups   Esc - Back", 20, game.ScreenY-40, true)
+		if gamepadConnected() {
+			game.drawString(game.Framebuffer, "Pad: A - Play   X - New   LB - Duplicate   Select - Delete   RB - Back Up   Y - Backups", 20, game.ScreenY-70, true)
+		}
+	}
+
+	if menu.Message != "" {
+		game.drawString(game.Framebuffer, menu.Message, 20, game.ScreenY-22, true)
+	}
+
+	screen.DrawImage(game.Framebuffer, nil)
+
+	return nil
+}
+
+// draw the worlds with the selected one's preview next to them
+func drawWorldList(game *Game, menu *WorldMenu) {
+	if len(menu.Worlds) == 0 {
+		game.drawString(game.Framebuffer, "No worlds yet, press N to make one", 20, 50, true)
+		return
+	}
+
+	// scroll so the selected world is always on screen
+	visibleRows := max(1, (game.ScreenY-110)/worldListRowHeight)
+	first := max(0, menu.Selected-visibleRows+1)
+
+	for i := first; i < len(menu.Worlds) && i < first+visibleRows; i++ {
+		world := menu.Worlds[i]
+		y := 50 + (i-first)*worldListRowHeight
+
+		prefix := "  "
+		if i == menu.Selected {
+			prefix = "> "
+		}
+		lastPlayed := "never"
+		if !world.LastPlayed.IsZero() {
+			lastPlayed = world.LastPlayed.Format("2006-01-02 15:04")
+		}
+		game.drawString(game.Framebuffer, prefix+world.Name, 20, y, true)
+		game.drawString(game.Framebuffer, fmt.Sprintf("    Seed %d, last played %s", world.Seed, lastPlayed), 20, y+12, true)
+	}
+
+	// preview of the selected world
+	if preview := menu.preview(menu.Worlds[menu.Selected].Path); preview != nil {
+		op := &ebiten.DrawImageOptions{}
+		op.GeoM.Translate(float64(game.ScreenX-preview.Bounds().Dx()-20), 50)
+		game.Framebuffer.DrawImage(preview, op)
+	}
+}
+
+// draw the backups of the selected world
+func drawBackupList(game *Game, menu *WorldMenu) {
+	game.drawString(game.Framebuffer, fmt.Sprintf("BACKUPS OF %s", menu.Worlds[menu.Selected].Name), 20, 50, true)
+	if len(menu.Backups) == 0 {
+		game.drawString(game.Framebuffer, "No backups yet, press B on the world list to make one", 20, 70, true)
+		return
+	}
+
+	visibleRows := max(1, (game.ScreenY-130)/15)
+	first := max(0, menu.SelectedBackup-visibleRows+1)
+	for i := first; i < len(menu.Backups) && i < first+visibleRows; i++ {
+		backup := menu.Backups[i]
+		prefix := "  "
+		if i == menu.SelectedBackup {
+			prefix = "> "
+		}
+		line := fmt.Sprintf("%s%s  %s  %d KB", prefix, backup.Time.Format("2006-01-02 15:04:05"), backup.Reason, backup.Size/1024)
+		game.drawString(game.Framebuffer, line, 20, 70+(i-first)*15, true)
+	}
+}
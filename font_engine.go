@@ -81,6 +81,17 @@ func (f *Font) getCharWidthIdx(idx int) (width int) {
 	return
 }
 
+// stringWidth measures how wide a line of text is drawn
+func (f *Font) stringWidth(text string) (width int) {
+	for _, char := range text {
+		if char == rune(0x00) || char == '\n' {
+			continue
+		}
+		width += f.getCharWidthRune(char) + f.LetterPad
+	}
+	return max(0, width-f.LetterPad)
+}
+
 // renderString draws text to the screen with specified position and color
 func (f *Font) renderString(screen *ebiten.Image, text string, x, y int, color color.Color) {
 	cursorX := x
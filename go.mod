@@ -1,4 +1,4 @@
-module main
+module isometrica
 
 go 1.23.4
 
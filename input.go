@@ -0,0 +1,132 @@
+package main
+
+import (
+	"log"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// INPUT
+// reads the keyboard, mouse and gamepads through ebiten for the input state in input_state.go.
+
+// standard gamepad layout buttons by the names used in bindings, xbox style
+var gamepadButtonNames = map[string]ebiten.StandardGamepadButton{
+	"A":             ebiten.StandardGamepadButtonRightBottom,
+	"B":             ebiten.StandardGamepadButtonRightRight,
+	"X":             ebiten.StandardGamepadButtonRightLeft,
+	"Y":             ebiten.StandardGamepadButtonRightTop,
+	"LeftShoulder":  ebiten.StandardGamepadButtonFrontTopLeft,
+	"RightShoulder": ebiten.StandardGamepadButtonFrontTopRight,
+	"LeftTrigger":   ebiten.StandardGamepadButtonFrontBottomLeft,
+	"RightTrigger":  ebiten.StandardGamepadButtonFrontBottomRight,
+	"Back":          ebiten.StandardGamepadButtonCenterLeft,
+	"Start":         ebiten.StandardGamepadButtonCenterRight,
+	"Home":          ebiten.StandardGamepadButtonCenterCenter,
+	"LeftStick":     ebiten.StandardGamepadButtonLeftStick,
+	"RightStick":    ebiten.StandardGamepadButtonRightStick,
+	"DpadUp":        ebiten.StandardGamepadButtonLeftTop,
+	"DpadDown":      ebiten.StandardGamepadButtonLeftBottom,
+	"DpadLeft":      ebiten.StandardGamepadButtonLeftLeft,
+	"DpadRight":     ebiten.StandardGamepadButtonLeftRight,
+}
+
+// mouse buttons by the names used in bindings
+var mouseButtonNames = map[string]ebiten.MouseButton{
+	"Left":   ebiten.MouseButtonLeft,
+	"Right":  ebiten.MouseButtonRight,
+	"Middle": ebiten.MouseButtonMiddle,
+}
+
+// the mouse wheel directions, these are only held so they can be bound, see Input.WheelSteps
+var mouseWheelNames = []string{"WheelUp", "WheelDown"}
+
+// the real keyboard, mouse and gamepads
+type ebitenInput struct{}
+
+func (ebitenInput) HeldInputs() []string {
+	var names []string
+	for _, key := range inpututil.AppendPressedKeys(nil) {
+		names = append(names, key.String())
+	}
+	for name, button := range mouseButtonNames {
+		if ebiten.IsMouseButtonPressed(button) {
+			names = append(names, "Mouse "+name)
+		}
+	}
+	if _, wheel := ebiten.Wheel(); wheel > 0 {
+		names = append(names, "Mouse WheelUp")
+	} else if wheel < 0 {
+		names = append(names, "Mouse WheelDown")
+	}
+	for _, id := range ebiten.AppendGamepadIDs(nil) {
+		if !ebiten.IsStandardGamepadLayoutAvailable(id) {
+			continue
+		}
+		for name, button := range gamepadButtonNames {
+			if ebiten.IsStandardGamepadButtonPressed(id, button) {
+				names = append(names, "Pad "+name)
+			}
+		}
+	}
+	return names
+}
+
+func (ebitenInput) Wheel() float64 {
+	_, y := ebiten.Wheel()
+	return y
+}
+
+func (ebitenInput) Sticks() [2][2]float64 {
+	// whichever gamepad's stick is pushed furthest
+	var sticks [2][2]float64
+	axes := [2][2]ebiten.StandardGamepadAxis{
+		{ebiten.StandardGamepadAxisLeftStickHorizontal, ebiten.StandardGamepadAxisLeftStickVertical},
+		{ebiten.StandardGamepadAxisRightStickHorizontal, ebiten.StandardGamepadAxisRightStickVertical},
+	}
+	for _, id := range ebiten.AppendGamepadIDs(nil) {
+		if !ebiten.IsStandardGamepadLayoutAvailable(id) {
+			continue
+		}
+		for stick, axis := range axes {
+			x, y := ebiten.StandardGamepadAxisValue(id, axis[0]), ebiten.StandardGamepadAxisValue(id, axis[1])
+			if math.Hypot(x, y) > math.Hypot(sticks[stick][0], sticks[stick][1]) {
+				sticks[stick] = [2]float64{x, y}
+			}
+		}
+	}
+	return sticks
+}
+
+// is a gamepad plugged in
+func gamepadConnected() bool {
+	return len(ebiten.AppendGamepadIDs(nil)) > 0
+}
+
+// write key names the way ebiten reports them, so "Up" matches "ArrowUp".
+// anything bound to an input that doesn't exist is logged, it will just never be held.
+func normalizeBindings(bindings map[Action]Binding) {
+	for action, binding := range bindings {
+		for i, name := range binding.Keys {
+			var key ebiten.Key
+			if err := key.UnmarshalText([]byte(name)); err != nil {
+				log.Printf("Controls: %s is bound to unknown key %q", action, name)
+				continue
+			}
+			if key.String() != name {
+				binding.Keys[i] = key.String()
+			}
+		}
+		for _, name := range binding.Mouse {
+			if _, ok := mouseButtonNames[name]; !ok && !contains(mouseWheelNames, name) {
+				log.Printf("Controls: %s is bound to unknown mouse button %q", action, name)
+			}
+		}
+		for _, name := range binding.Gamepad {
+			if _, ok := gamepadButtonNames[name]; !ok && !contains(stickDirectionNames, name) {
+				log.Printf("Controls: %s is bound to unknown gamepad button %q", action, name)
+			}
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package main
+
+// INPUT STATE
+// keeps track of what has been held down from one frame to the next, so update functions can ask
+// whether something was just pressed, just let go, or how long it has been held.
+// inputs are named the way bindingInputs names them: key names, "Mouse Left", "Pad A" and so on.
+// gamepad sticks are kept as they are for analog movement, and pushing one most of the way also
+// holds an input like "Pad LeftStickUp", so the sticks can be bound and work in menus.
+// the mouse wheel is counted in notches rather than held, so every notch does something even when they come quickly.
+
+import "math"
+
+// how long a repeating action waits before it starts repeating, and how often it repeats after that, in frames
+const (
+	inputRepeatDelay    = 24
+	inputRepeatInterval = 5
+)
+
+// sticks
+const (
+	STICK_LEFT = iota
+	STICK_RIGHT
+)
+
+// how far a stick has to move before it counts, and how far before it holds a stick direction input
+const (
+	stickDeadzone  = .2
+	stickThreshold = .5
+)
+
+// stick directions by the names used in bindings
+var stickDirectionNames = []string{
+	"LeftStickUp", "LeftStickDown", "LeftStickLeft", "LeftStickRight",
+	"RightStickUp", "RightStickDown", "RightStickLeft", "RightStickRight",
+}
+
+// InputSource, where the input state reads what is down each frame.
+type InputSource interface {
+	HeldInputs() []string  // the names of every input held down right now
+	Sticks() [2][2]float64 // where the gamepad sticks are, each axis from -1 to 1, down is positive y
+	Wheel() float64        // how far the mouse wheel turned since last frame, up is positive
+}
+
+// InputState, how long every input has been held, updated once a frame.
+type InputState struct {
+	Frames   map[string]int  // frames each held input has been down, 1 on the frame it was pressed
+	Released map[string]bool // inputs let go this frame
+	Pressed  []string        // inputs pressed this frame, in the order the source gave them
+	Sticks   [2][2]float64   // where the sticks are this frame, with the deadzone taken out
+	Wheel    int             // whole wheel notches turned this frame, up is positive
+	wheel    float64         // what's left over of a notch, touchpads scroll a bit at a time
+}
+
+// take the deadzone out of a stick position, stretching what's left so it still goes from 0 to 1
+func applyDeadzone(x, y float64) (float64, float64) {
+	length := math.Hypot(x, y)
+	if length < stickDeadzone {
+		return 0, 0
+	}
+	scale := math.Min(length, 1)
+	scale = (scale - stickDeadzone) / (1 - stickDeadzone) / length
+	return x * scale, y * scale
+}
+
+// the stick direction inputs held by a stick position
+func stickInputs(sticks [2][2]float64) []string {
+	var names []string
+	for stick, position := range sticks {
+		directions := stickDirectionNames[stick*4 : stick*4+4]
+		if position[1] < -stickThreshold {
+			names = append(names, "Pad "+directions[0])
+		}
+		if position[1] > stickThreshold {
+			names = append(names, "Pad "+directions[1])
+		}
+		if position[0] < -stickThreshold {
+			names = append(names, "Pad "+directions[2])
+		}
+		if position[0] > stickThreshold {
+			names = append(names, "Pad "+directions[3])
+		}
+	}
+	return names
+}
+
+func newInputState() *InputState {
+	return &InputState{Frames: map[string]int{}, Released: map[string]bool{}}
+}
+
+// move on a frame, reading what is held from the source
+func (state *InputState) Update(source InputSource) {
+	for stick, position := range source.Sticks() {
+		x, y := applyDeadzone(position[0], position[1])
+		state.Sticks[stick] = [2]float64{x, y}
+	}
+
+	state.wheel += source.Wheel()
+	state.Wheel = int(state.wheel)
+	state.wheel -= float64(state.Wheel)
+
+	frames := make(map[string]int, len(state.Frames))
+	state.Pressed = state.Pressed[:0]
+	for _, name := range append(source.HeldInputs(), stickInputs(state.Sticks)...) {
+		if _, seen := frames[name]; seen {
+			continue
+		}
+		frames[name] = state.Frames[name] + 1
+		if frames[name] == 1 {
+			state.Pressed = append(state.Pressed, name)
+		}
+	}
+
+	clear(state.Released)
+	for name := range state.Frames {
+		if _, held := frames[name]; !held {
+			state.Released[name] = true
+		}
+	}
+	state.Frames = frames
+}
+
+func (state *InputState) JustPressed(name string) bool  { return state.Frames[name] == 1 }
+func (state *InputState) JustReleased(name string) bool { return state.Released[name] }
+func (state *InputState) HeldFrames(name string) int    { return state.Frames[name] }
+
+// Input, the input state seen through the bindings in the settings.
+// an action counts as held while any of its inputs are.
+type Input struct {
+	Bindings map[Action]Binding
+	Source   InputSource
+	State    *InputState
+}
+
+func newInput(source InputSource, bindings map[Action]Binding) *Input {
+	return &Input{Bindings: bindings, Source: source, State: newInputState()}
+}
+
+// read this frame's inputs, once at the start of every update
+func (input *Input) Update() {
+	input.State.Update(input.Source)
+}
+
+// how many frames the action has been held, going by whichever of its inputs has been held longest.
+// the wheel is never held, see WheelSteps.
+func (input *Input) HeldFrames(action Action) int {
+	longest := 0
+	for _, name := range bindingInputs(input.Bindings[action]) {
+		if !isWheelInput(name) {
+			longest = max(longest, input.State.HeldFrames(name))
+		}
+	}
+	return longest
+}
+
+// is an input the mouse wheel
+func isWheelInput(name string) bool {
+	return name == "Mouse WheelUp" || name == "Mouse WheelDown"
+}
+
+// how many wheel notches this frame went towards an action, through the wheel directions bound to it
+func (input *Input) WheelSteps(action Action) int {
+	steps := 0
+	for _, name := range bindingInputs(input.Bindings[action]) {
+		switch {
+		case name == "Mouse WheelUp" && input.State.Wheel > 0:
+			steps += input.State.Wheel
+		case name == "Mouse WheelDown" && input.State.Wheel < 0:
+			steps -= input.State.Wheel
+		}
+	}
+	return steps
+}
+
+// how many times an action happens this frame, one for its trigger and one for every wheel notch
+func (input *Input) Times(action Action) int {
+	times := input.WheelSteps(action)
+	if input.triggered(action) {
+		times++
+	}
+	return times
+}
+
+func (input *Input) Held(action Action) bool {
+	return input.HeldFrames(action) > 0
+}
+
+// did the action start this frame. pressing a second input for an action that is already held doesn't count.
+func (input *Input) JustPressed(action Action) bool {
+	return input.HeldFrames(action) == 1
+}
+
+// did the action stop this frame, with nothing else bound to it still held
+func (input *Input) JustReleased(action Action) bool {
+	if input.Held(action) {
+		return false
+	}
+	for _, name := range bindingInputs(input.Bindings[action]) {
+		if input.State.JustReleased(name) && !isWheelInput(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// just pressed, then every few frames once it has been held long enough
+func (input *Input) Repeat(action Action) bool {
+	frames := input.HeldFrames(action)
+	return frames == 1 || (frames > inputRepeatDelay && (frames-inputRepeatDelay)%inputRepeatInterval == 0)
+}
+
+// is an action happening this frame, going by its trigger or a turn of the wheel
+func (input *Input) Active(action Action) bool {
+	return input.triggered(action) || input.WheelSteps(action) > 0
+}
+
+// is an action happening this frame, going by its trigger
+func (input *Input) triggered(action Action) bool {
+	info, _ := actionInfo(action)
+	switch info.Trigger {
+	case TRIGGER_HOLD:
+		return input.Held(action)
+	case TRIGGER_REPEAT:
+		return input.Repeat(action)
+	}
+	return input.JustPressed(action)
+}
+
+// where a stick is, with the deadzone taken out
+func (input *Input) Stick(stick int) (x, y float64) {
+	return input.State.Sticks[stick][0], input.State.Sticks[stick][1]
+}
+
+// was an input pressed this frame, for menu shortcuts that aren't actions
+func (input *Input) InputJustPressed(name string) bool {
+	return input.State.JustPressed(name)
+}
+
+// the first input pressed this frame, as a binding with just that in it.
+// used by the controls menu to rebind actions.
+func (input *Input) PressedBinding() (binding Binding, ok bool) {
+	if len(input.State.Pressed) == 0 {
+		return Binding{}, false
+	}
+	return inputBinding(input.State.Pressed[0]), true
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+// an input source that plays back a list of frames
+type scriptedInput struct {
+	Frames      [][]string
+	StickFrames [][2][2]float64 // stick positions for each frame, centered when left out
+	WheelFrames []float64       // how far the wheel turns each frame, still when left out
+	Frame       int
+}
+
+func (source *scriptedInput) Wheel() float64 {
+	if source.Frame >= len(source.WheelFrames) {
+		return 0
+	}
+	return source.WheelFrames[source.Frame]
+}
+
+func (source *scriptedInput) Sticks() [2][2]float64 {
+	if source.Frame >= len(source.StickFrames) {
+		return [2][2]float64{}
+	}
+	return source.StickFrames[source.Frame]
+}
+
+func (source *scriptedInput) HeldInputs() []string {
+	if source.Frame >= len(source.Frames) {
+		return nil
+	}
+	held := source.Frames[source.Frame]
+	source.Frame++
+	return held
+}
+
+// run an input through a script, calling check after every frame
+func playInput(input *Input, frames [][]string, check func(frame int)) {
+	input.Source = &scriptedInput{Frames: frames}
+	for frame := range frames {
+		input.Update()
+		check(frame)
+	}
+}
+
+func TestInputStatePressAndRelease(t *testing.T) {
+	state := newInputState()
+	source := &scriptedInput{Frames: [][]string{{}, {"F3"}, {"F3"}, {"F3"}, {}, {}}}
+
+	var pressed, released, held []int
+	for frame := range source.Frames {
+		state.Update(source)
+		if state.JustPressed("F3") {
+			pressed = append(pressed, frame)
+		}
+		if state.JustReleased("F3") {
+			released = append(released, frame)
+		}
+		held = append(held, state.HeldFrames("F3"))
+	}
+
+	if !reflect.DeepEqual(pressed, []int{1}) {
+		t.Errorf("F3 was just pressed on frames %v, want only frame 1", pressed)
+	}
+	if !reflect.DeepEqual(released, []int{4}) {
+		t.Errorf("F3 was just released on frames %v, want only frame 4", released)
+	}
+	if want := []int{0, 1, 2, 3, 0, 0}; !reflect.DeepEqual(held, want) {
+		t.Errorf("F3 held for %v frames, want %v", held, want)
+	}
+}
+
+func TestInputToggleFlipsOncePerPress(t *testing.T) {
+	input := newInput(nil, defaultBindings())
+	debug := false
+
+	// hold F3 for a while, let go, then press it again
+	frames := [][]string{{"F3"}, {"F3"}, {"F3"}, {"F3"}, {}, {"F3"}, {}}
+	playInput(input, frames, func(int) {
+		if input.Active(ACTION_DEBUG) {
+			debug = !debug
+		}
+	})
+	if debug {
+		t.Error("debug mode is on after pressing F3 twice")
+	}
+}
+
+func TestInputActionWithSeveralBindings(t *testing.T) {
+	input := newInput(nil, defaultBindings())
+
+	// Q then the left arrow, both rotate left, overlapping
+	frames := [][]string{{"Q"}, {"Q", "ArrowLeft"}, {"ArrowLeft"}, {}}
+	var pressed, released []int
+	playInput(input, frames, func(frame int) {
+		if input.JustPressed(ACTION_ROTATE_LEFT) {
+			pressed = append(pressed, frame)
+		}
+		if input.JustReleased(ACTION_ROTATE_LEFT) {
+			released = append(released, frame)
+		}
+	})
+	if !reflect.DeepEqual(pressed, []int{0}) {
+		t.Errorf("rotate left started on frames %v, want only frame 0", pressed)
+	}
+	if !reflect.DeepEqual(released, []int{3}) {
+		t.Errorf("rotate left stopped on frames %v, want only frame 3", released)
+	}
+}
+
+func TestInputMenuRepeat(t *testing.T) {
+	input := newInput(nil, defaultBindings())
+
+	frames := make([][]string, inputRepeatDelay+inputRepeatInterval*2)
+	for i := range frames {
+		frames[i] = []string{"ArrowDown"}
+	}
+	moves := 0
+	playInput(input, frames, func(int) {
+		if input.Active(ACTION_MENU_DOWN) {
+			moves++
+		}
+	})
+	if moves != 3 {
+		t.Errorf("holding down moved %d times, want 3", moves)
+	}
+}
+
+func TestInputPressedBinding(t *testing.T) {
+	input := newInput(nil, defaultBindings())
+
+	// enter is already held when rebinding starts, so only the new press counts
+	frames := [][]string{{"Enter"}, {"Enter"}, {"Enter", "Pad Y"}}
+	var bindings []Binding
+	playInput(input, frames, func(int) {
+		if binding, ok := input.PressedBinding(); ok {
+			bindings = append(bindings, binding)
+		}
+	})
+	want := []Binding{{Keys: []string{"Enter"}}, {Gamepad: []string{"Y"}}}
+	if !reflect.DeepEqual(bindings, want) {
+		t.Errorf("pressed bindings %+v, want %+v", bindings, want)
+	}
+}
+
+func TestApplyDeadzone(t *testing.T) {
+	tests := []struct{ x, y, wantX, wantY float64 }{
+		{.1, -.1, 0, 0},
+		{1, 0, 1, 0},
+		{0, -.6, 0, -.5},
+		{2, 0, 1, 0},
+	}
+	for _, test := range tests {
+		x, y := applyDeadzone(test.x, test.y)
+		if math.Abs(x-test.wantX) > 1e-9 || math.Abs(y-test.wantY) > 1e-9 {
+			t.Errorf("applyDeadzone(%g, %g) = %g, %g, want %g, %g", test.x, test.y, x, y, test.wantX, test.wantY)
+		}
+	}
+}
+
+func TestInputStickNavigatesMenus(t *testing.T) {
+	input := newInput(nil, defaultBindings())
+
+	// push the stick down, let it drift back a bit without crossing back over the threshold, then let go
+	source := &scriptedInput{
+		Frames:      make([][]string, 4),
+		StickFrames: [][2][2]float64{{{0, .9}}, {{0, .7}}, {{0, .1}}, {{0, .9}}},
+	}
+	input.Source = source
+	moves := 0
+	for range source.Frames {
+		input.Update()
+		if input.Active(ACTION_MENU_DOWN) {
+			moves++
+		}
+	}
+	if moves != 2 {
+		t.Errorf("the stick moved down the menu %d times, want 2", moves)
+	}
+	if x, y := input.Stick(STICK_LEFT); x != 0 || y <= .8 {
+		t.Errorf("left stick = %g, %g, want it most of the way down", x, y)
+	}
+}
+
+func TestInputWheelCountsNotches(t *testing.T) {
+	bindings := map[Action]Binding{
+		ACTION_CUTAWAY_UP:   {Keys: []string{"PageUp"}, Mouse: []string{"WheelUp"}},
+		ACTION_CUTAWAY_DOWN: {Mouse: []string{"WheelDown"}},
+	}
+	input := newInput(nil, bindings)
+
+	// notches on back to back frames, two at once, and a touchpad scrolling half a notch at a time
+	wheel := []float64{1, 1, 2, 0, .5, .5, -1}
+	input.Source = &scriptedInput{Frames: make([][]string, len(wheel)), WheelFrames: wheel}
+	var up, down []int
+	for range wheel {
+		input.Update()
+		up = append(up, input.Times(ACTION_CUTAWAY_UP))
+		down = append(down, input.Times(ACTION_CUTAWAY_DOWN))
+	}
+	if !reflect.DeepEqual(up, []int{1, 1, 2, 0, 0, 1, 0}) {
+		t.Errorf("cutaway up happened %v times a frame", up)
+	}
+	if !reflect.DeepEqual(down, []int{0, 0, 0, 0, 0, 0, 1}) {
+		t.Errorf("cutaway down happened %v times a frame", down)
+	}
+
+	// the wheel turning every frame isn't a key being held, so it doesn't repeat
+	if input.HeldFrames(ACTION_CUTAWAY_UP) != 0 {
+		t.Errorf("wheel counts as held")
+	}
+}
@@ -1,10 +1,11 @@
 package main
 
 import (
+	"image/color"
 	"log"
-	"math/rand/v2"
 	"net/http"
-	"path/filepath"
+	"os"
+	"sync"
 	"time"
 	"unsafe"
 
@@ -18,19 +19,78 @@ type GameState int
 
 const (
 	GAMESTATE_TITLE GameState = iota
+	GAMESTATE_WORLDS
 	GAMESTATE_MENU
+	GAMESTATE_SETTINGS
+	GAMESTATE_CONTROLS
 	GAMESTATE_GAME
+	GAMESTATE_MAP
+	GAMESTATE_WAYPOINTS
 )
 
 type Game struct {
-	GameState          GameState // gamestate enum
-	HasInitiatedUpdate bool      // init update flag
-	HasInitiatedDraw   bool      // init draw flag
-	DebugMode          bool      // are we debugging?
-
-	World        World  // in-game world position
-	Player       Player // player context
-	CurrentChunk [2]int // global chunk location of player
+	GameState        GameState // gamestate enum
+	HasInitiatedDraw bool      // init draw flag
+	DebugMode        bool      // are we debugging?
+	XRayMode         bool      // only render ores, debug only
+	WorldMenu        WorldMenu // world list screen
+	PauseSelection   int       // selected pause menu item
+	Input            *Input    // what the controls are bound to
+
+	Settings         Settings // user settings, see settings.go
+	SettingsPath     string   // where the settings are saved, empty if there's nowhere to save them
+	SettingSelection int      // selected settings menu item
+	ControlSelection int      // selected action in the controls menu
+	Rebinding        bool     // waiting for an input to bind to the selected action
+
+	World        World          // in-game world position
+	SaveLock     *SaveLock      // lock on the save directory, so two instances don't write to it
+	SaveMutex    sync.Mutex     // held while anything is being written to the save
+	SyncStop     chan struct{}  // closed to stop syncWorldWithDisk
+	SyncDone     chan struct{}  // closed by syncWorldWithDisk when it has stopped
+	SyncWake     chan struct{}  // makes syncWorldWithDisk run a pass now, see wakeSync
+	Sync         SyncState      // what syncWorldWithDisk loads around, see setSyncState
+	SyncMutex    sync.Mutex     // guards Sync
+	Saves        sync.WaitGroup // saves running in the background, see SaveWorldInBackground
+	LastBackup   time.Time      // when the open world was last backed up
+	Player       Player         // player context
+	CurrentChunk [2]int         // global chunk location of player
+	Map          *WorldMap      // what the player has explored, see world_map.go
+
+	HeldVoxel     string     // what gets placed
+	Cursor        [2]float64 // where blocks are targeted on screen, the mouse or the gamepad cursor
+	GamepadCursor bool       // is the gamepad moving the cursor, rather than the mouse
+	LastMouse     [2]int     // where the mouse was last frame
+	Target        [3]int     // voxel under the cursor
+	TargetBefore  [3]int     // voxel in front of the face of Target the cursor is on
+	HasTarget     bool       // is there a voxel under the cursor at all
+
+	Cutaway       bool     // hide or fade everything above a slice through the world, see cutawayTop
+	CutawayOffset int      // how far the slice is above the player's head
+	ViewRadius    int      // how many chunks out from the player's chunk can be on screen
+	VisibleChunks [][2]int // the chunks that can be on screen, in the order they're drawn, see visibleChunks
+
+	MapCenter  [2]float64    // the column in the middle of the full-screen map
+	MapScale   float64       // screen pixels per voxel on the full-screen map, one of mapScales
+	MapImage   *ebiten.Image // the full-screen map as it was last painted
+	MapPainted [6]float64    // what MapImage was painted with, so it's only painted again when something changes
+	Minimap    *ebiten.Image // the minimap, painted every frame
+
+	Waypoints         []Waypoint // named places in the open world, see waypoints.go
+	WaypointSelection int        // selected line in the waypoints menu
+	WaypointNaming    bool       // typing the name of a new waypoint
+	WaypointName      string     // the name being typed
+	WaypointMessage   string     // last error or result in the waypoints menu
+	Teleport          chan Vec3  // gets where to go once the chunks there are loaded, nil when not teleporting
+	TeleportTarget    Vec3       // where the teleport is going
+
+	ScreenshotPending bool        // take a screenshot the next time the world is drawn
+	Notices           chan string // results of things finished in the background, like writing a screenshot
+	Notice            string      // the last notice, shown for a while at the bottom of the screen
+	NoticeTime        time.Time   // when Notice arrived
+
+	SavedWorldData  []byte // world.json as it was last written, so unchanged data isn't written again
+	SavedPlayerData []byte // player.json as it was last written
 
 	Camera              [2]float32 // camera location
 	Direction           [4]int     // rotation factor
@@ -45,6 +105,7 @@ type Game struct {
 	Framebuffer *ebiten.Image // image destination
 	ScreenX     int           // width of the screen
 	ScreenY     int           // height of the screen
+	WindowWidth int           // width of the window, for the zoom levels that do something in it
 	Font        *Font         // global font
 
 	ChunkSize  int // size of the chunk, for generation
@@ -68,66 +129,92 @@ func MapSize[K comparable, V any](m map[K]V) uintptr {
 	return totalSize
 }
 
+// the logical screen is the window divided by a whole number, see zoom.go
 func (g *Game) Layout(outsideWidth, outsideHeight int) (int, int) {
-	return 640, (640 * outsideHeight) / outsideWidth
+	g.WindowWidth = outsideWidth
+	// menus stay the same size whatever the window, DrawFinalScreen scales them up
+	if g.GameState != GAMESTATE_GAME {
+		return screenWidth, screenHeight
+	}
+	scale := pixelScale(outsideWidth, windowZoom(outsideWidth, g.Settings.Zoom))
+	return max(outsideWidth/scale, 1), max(outsideHeight/scale, 1)
+}
+
+// scale the logical screen up to the window by a whole number, without any filtering, so every pixel stays square
+func (g *Game) DrawFinalScreen(screen ebiten.FinalScreen, offscreen *ebiten.Image, geoM ebiten.GeoM) {
+	screenWidth, screenHeight := screen.Bounds().Dx(), screen.Bounds().Dy()
+	width, height := offscreen.Bounds().Dx(), offscreen.Bounds().Dy()
+	scale := max(1, min(screenWidth/width, screenHeight/height))
+
+	screen.Fill(color.Black)
+	op := &ebiten.DrawImageOptions{Filter: ebiten.FilterNearest}
+	op.GeoM.Scale(float64(scale), float64(scale))
+	op.GeoM.Translate(float64((screenWidth-width*scale)/2), float64((screenHeight-height*scale)/2))
+	screen.DrawImage(offscreen, op)
 }
 
 func main() {
+	// run a subcommand instead of the game if there is one
+	if len(os.Args) > 1 {
+		if err := runCommand(os.Args[1], os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	go func() {
 		log.Println(http.ListenAndServe("localhost:6060", nil))
 	}()
 
 	// init game
 	game := &Game{
-		HasInitiatedDraw:   false,
-		HasInitiatedUpdate: false,
-		CurrentChunk:       [2]int{0, 0},
-		ChunkSize:          32,
-		ChunkDepth:         64,
-		GameState:          GAMESTATE_TITLE,
-		UsingDepthShift:    true,
+		HasInitiatedDraw: false,
+		CurrentChunk:     [2]int{0, 0},
+		ChunkSize:        32,
+		ChunkDepth:       64,
+		GameState:        GAMESTATE_TITLE,
+		HeldVoxel:        "Cobblestone",
+		UsingDepthShift:  true,
+		Notices:          make(chan string, 8),
+	}
+
+	// load settings, a broken settings file shouldn't stop the game from starting
+	var err error
+	game.SettingsPath, err = settingsPath()
+	if err != nil {
+		log.Printf("ERROR: Nowhere to save settings: %v", err)
+		game.Settings = defaultSettings()
+	} else {
+		var problems []string
+		game.Settings, problems, err = loadSettings(game.SettingsPath)
+		if err != nil {
+			log.Printf("ERROR: Failed to load settings, using defaults: %v", err)
+		}
+		for _, problem := range problems {
+			log.Printf("Settings: %s", problem)
+		}
 	}
 
 	// init ebiten
-	initialWidth, initialHeight := 1280, 720
-	ebiten.SetTPS(60)
-	// SetVsyncEnabled(false) is only for debug purposes
-	// It is greatly recommended to use SetVsyncEnabled(true) in production
-	ebiten.SetVsyncEnabled(!game.DebugMode)
-	ebiten.SetWindowSize(initialWidth, initialHeight)
+	game.Input = newInput(ebitenInput{}, game.Settings.Bindings)
+	game.applySettings(true)
 	ebiten.SetWindowTitle("ISOMETRICA Infdev")
 	ebiten.SetWindowResizingMode(ebiten.WindowResizingModeEnabled)
 
 	// init render
 	initRender()
 
-	// init player, needs to happen before world is loaded
-	game.Player = Player{
-		Position: Vec3{0, 0, float32(game.World.SurfaceFeaturesBeginAt) + 10},
-		Velocity: Vec3{0, 0, 0},
-		Drag:     Vec3{.9, .9, .9},
-		Texture:  "Default",
-	}
-
-	// check if there is a save file at the save path
-	savePath := "save/demo"
-	err := game.LoadGame(savePath)
-	if err != nil {
-		log.Printf("Failed to load world: %v", err)
-		log.Println("Creating new world ...")
-
-		// create new world
-
-		game.World = World{}
-		game.World.Initialize(rand.Int64())
-		game.World.SavePath = filepath.Join("save", "demo")
-		game.MakeEmptySave()
-	} else {
-		log.Println("Loading world from file.")
+	// worlds are picked on the world list, nothing is loaded until then
+	if err := os.MkdirAll(savesPath, 0755); err != nil {
+		log.Fatal(err)
 	}
 
 	// run the game
-	if err := ebiten.RunGame(game); err != nil {
+	err = ebiten.RunGame(game)
+
+	// save and let go of the open world before quitting
+	game.CloseWorld()
+	if err != nil {
 		log.Fatal(err)
 	}
 }
@@ -43,42 +43,70 @@ func (v Vec3) Normalize() Vec3 {
 // 	return 0
 // }
 
-func pointsMakeCCWTurn(A, B, C Vec2i) bool {
-	return (B.X-A.X)*(C.Y-A.Y) > (C.X-A.X)*(B.Y-A.Y)
+func absi(x int) int {
+	if x < 0 {
+		return -x
+	} else {
+		return x
+	}
 }
 
-func doLinesIntersect(A, B, C, D Vec2i) bool {
-	return pointsMakeCCWTurn(A, C, D) != pointsMakeCCWTurn(B, C, D) && pointsMakeCCWTurn(A, B, C) != pointsMakeCCWTurn(A, B, D)
+// integer division that rounds towards negative infinity
+func floorDiv(a, b int) int {
+	q := a / b
+	if (a%b != 0) && ((a < 0) != (b < 0)) {
+		q--
+	}
+	return q
 }
 
-// checks if a point is in a rectangle defined by width and height
-func isPointInBounds(x, y, width, height int) bool {
-	return x >= 0 && y >= 0 && x < width && y < height
+// turn movement on the screen, x right and y down, into movement across the world for a camera direction.
+// how hard it's pushed is kept up to 1, so diagonals aren't faster and a half pushed stick is half speed.
+func screenToWorldMovement(direction [4]int, x, y float32) Vec3 {
+	strength := float32(math.Min(math.Hypot(float64(x), float64(y)), 1))
+	if strength == 0 {
+		return Vec3{}
+	}
+
+	// a voxel step moves twice as far across the screen as down it, undo that, then undo the direction
+	sxX, sxY, syX, syY := float32(direction[0]), float32(direction[1]), float32(direction[2]), float32(direction[3])
+	y *= 2
+	det := sxX*syY - syX*sxY
+	world := Vec3{X: (syY*x - syX*y) / det, Y: (sxX*y - sxY*x) / det}.Normalize()
+	return Vec3{X: world.X * strength, Y: world.Y * strength}
 }
 
-// checks if a line intersects a rectangle defined by width and height and starting point
-func doesLineIntersectRectangle(x, y, x2, y2, rx, ry, rWidth, rHeight int) bool {
-	// check if line points are in the rectangle
-	if isPointInBounds(x, y, rWidth, rHeight) || isPointInBounds(x2, y2, rWidth, rHeight) {
-		return true
-	}
+// the remainder of floorDiv, never negative for a positive b
+func floorMod(a, b int) int {
+	return a - floorDiv(a, b)*b
+}
 
-	// check intersections with the top and bottom edges
-	if doLinesIntersect(Vec2i{x, y}, Vec2i{x2, y2}, Vec2i{rx, ry}, Vec2i{rx + rWidth, ry}) || doLinesIntersect(Vec2i{x, y}, Vec2i{x2, y2}, Vec2i{rx, ry + rHeight}, Vec2i{rx + rWidth, ry + rHeight}) {
-		return true
+// clamp an int between lo and hi
+func clampi(x, lo, hi int) int {
+	if x < lo {
+		return lo
 	}
-	// check intersections with the left and right edges
-	if doLinesIntersect(Vec2i{x, y}, Vec2i{x2, y2}, Vec2i{rx, ry}, Vec2i{rx, ry + rHeight}) || doLinesIntersect(Vec2i{x, y}, Vec2i{x2, y2}, Vec2i{rx + rWidth, ry}, Vec2i{rx + rWidth, ry + rHeight}) {
-		return true
+	if x > hi {
+		return hi
 	}
+	return x
+}
 
-	return false
+// clamp a float between lo and hi
+func clampf(x, lo, hi float64) float64 {
+	return math.Max(lo, math.Min(x, hi))
 }
 
-func absi(x int) int {
-	if x < 0 {
-		return -x
-	} else {
-		return x
+// hash a seed and a 2D coordinate into a pseudo random number.
+// salt lets different systems get different numbers for the same coordinate.
+func hashCoords(seed int64, x, y, salt int) uint64 {
+	// splitmix64
+	h := uint64(seed)
+	for _, n := range []int{x, y, salt} {
+		h += uint64(n) + 0x9e3779b97f4a7c15
+		h = (h ^ (h >> 30)) * 0xbf58476d1ce4e5b9
+		h = (h ^ (h >> 27)) * 0x94d049bb133111eb
+		h ^= h >> 31
 	}
+	return h
 }
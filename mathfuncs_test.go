@@ -0,0 +1,35 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestScreenToWorldMovement(t *testing.T) {
+	// wherever the camera faces, moving up the screen should draw the player further up it, and not sideways
+	for _, direction := range [][4]int{SOUTH, WEST, NORTH, EAST} {
+		for _, move := range [][2]float32{{0, -1}, {1, 0}, {0, 1}, {-1, 0}, {1, -1}} {
+			movement := screenToWorldMovement(direction, move[0], move[1])
+
+			length := math.Hypot(float64(movement.X), float64(movement.Y))
+			if math.Abs(length-1) > 1e-5 {
+				t.Errorf("%s %v: moved %g, want 1", directionName(direction), move, length)
+			}
+
+			// where the movement ends up on the screen
+			screenX, screenY := worldToScreenMovement(direction, movement.X, movement.Y)
+			sideways := float64(screenX*move[1] - screenY*move[0])
+			forwards := float64(screenX*move[0] + screenY*move[1])
+			if math.Abs(sideways) > 1e-5 || forwards <= 0 {
+				t.Errorf("%s %v: moved the player to %g, %g on the screen", directionName(direction), move, screenX, screenY)
+			}
+		}
+	}
+
+	if movement := screenToWorldMovement(SOUTH, .5, 0); math.Abs(math.Hypot(float64(movement.X), float64(movement.Y))-.5) > 1e-5 {
+		t.Errorf("half a stick moved %+v, want half speed", movement)
+	}
+	if movement := screenToWorldMovement(SOUTH, 0, 0); movement != (Vec3{}) {
+		t.Errorf("no input moved %+v", movement)
+	}
+}
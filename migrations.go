@@ -0,0 +1,202 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SAVE MIGRATIONS
+// every change to the save format gets a step here that upgrades a save from the version before it.
+// old saves are backed up and then upgraded in place, one step at a time, when they are loaded.
+
+// SaveMigration, upgrades a save directory from one format version to the next.
+type SaveMigration struct {
+	From        int
+	To          int
+	Description string
+	Migrate     func(savePath string) error
+}
+
+// every migration step, in order
+var saveMigrations = []SaveMigration{
+	{From: 0, To: 1, Description: "run-length encode chunks and version world.json", Migrate: migrateSaveV0ToV1},
+}
+
+// read the format version of a save without decoding the rest of it.
+// saves from before versioning have no version field, which reads as 0.
+func readSaveFormatVersion(savePath string) (version int, err error) {
+	data, err := os.ReadFile(filepath.Join(savePath, "world.json"))
+	if err != nil {
+		return 0, err
+	}
+	var header struct {
+		FormatVersion int `json:"format_version"`
+	}
+	if err = json.Unmarshal(data, &header); err != nil {
+		return 0, err
+	}
+	return header.FormatVersion, nil
+}
+
+// set the format version in world.json, keeping every other field as it is
+func writeSaveFormatVersion(savePath string, version int) error {
+	path := filepath.Join(savePath, "world.json")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var fields map[string]any
+	if err = json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	fields["format_version"] = version
+
+	data, err = json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data, 0644)
+}
+
+// upgrade a save to SaveFormatVersion, backing it up first if anything needs to change
+func migrateSave(savePath string) error {
+	version, err := readSaveFormatVersion(savePath)
+	if err != nil {
+		return err
+	}
+	if version > SaveFormatVersion {
+		return fmt.Errorf("save is format version %d, but this build only knows up to %d", version, SaveFormatVersion)
+	}
+	if version == SaveFormatVersion {
+		return nil
+	}
+
+	backupPath, err := createBackup(savePath, fmt.Sprintf("before-migration-v%d", version))
+	if err != nil {
+		return fmt.Errorf("failed to back up save before migrating: %v", err)
+	}
+	log.Printf("Backed up save to %s", backupPath)
+
+	for _, migration := range saveMigrations {
+		if migration.From != version {
+			continue
+		}
+		log.Printf("Migrating save from version %d to %d: %s", migration.From, migration.To, migration.Description)
+		if err := migration.Migrate(savePath); err != nil {
+			return fmt.Errorf("migration from version %d to %d failed, the backup is at %s: %v", migration.From, migration.To, backupPath, err)
+		}
+		if err := writeSaveFormatVersion(savePath, migration.To); err != nil {
+			return err
+		}
+		version = migration.To
+	}
+
+	if version != SaveFormatVersion {
+		return fmt.Errorf("no migration path from version %d to %d", version, SaveFormatVersion)
+	}
+	return nil
+}
+
+// copy everything in a save directory to a new directory, except for its lock
+func copySaveDirectory(savePath, targetPath string) error {
+	return filepath.Walk(savePath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		// the lock belongs to whoever is running right now, not to the backup
+		if info.Name() == "session.lock" {
+			return nil
+		}
+		relative, err := filepath.Rel(savePath, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(targetPath, relative)
+
+		if info.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		return copyFile(path, target)
+	})
+}
+
+// copy a single file
+func copyFile(source, target string) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// version 0 to 1: chunks go from a name table with one entry per voxel to run-length encoded palettes.
+// chunks that are already run-length encoded are skipped, so a migration that was cut off can be run again.
+func migrateSaveV0ToV1(savePath string) error {
+	terrainPath := filepath.Join(savePath, "terrain")
+	entries, err := os.ReadDir(terrainPath)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasPrefix(entry.Name(), "chunk") || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		path := filepath.Join(terrainPath, entry.Name())
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var fields map[string]json.RawMessage
+		if err = json.Unmarshal(data, &fields); err != nil {
+			return fmt.Errorf("%s: %v", entry.Name(), err)
+		}
+		_, hasPalette := fields["palette"]
+		_, hasRuns := fields["runs"]
+		if hasPalette || hasRuns {
+			continue
+		}
+
+		var oldChunk DebugChunkJSON
+		if err = json.Unmarshal(data, &oldChunk); err != nil {
+			return fmt.Errorf("%s: %v", entry.Name(), err)
+		}
+		names, err := oldChunk.voxelNames()
+		if err != nil {
+			return fmt.Errorf("%s: %v", entry.Name(), err)
+		}
+		// rewriting a chunk with the wrong number of voxels would lose it, leave it for the backup
+		if len(names) != oldChunk.Width*oldChunk.Height*oldChunk.Depth {
+			return fmt.Errorf("%s: has %d voxels, expected %d", entry.Name(), len(names), oldChunk.Width*oldChunk.Height*oldChunk.Depth)
+		}
+
+		newChunk := ChunkJSON{Width: oldChunk.Width, Height: oldChunk.Height, Depth: oldChunk.Depth}
+		newChunk.Palette, newChunk.Runs = encodeVoxelRuns(names)
+		data, err = json.Marshal(newChunk)
+		if err != nil {
+			return err
+		}
+		if err = writeFileAtomic(path, data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"math"
+
+	"github.com/aquilax/go-perlin"
+)
+
+// UNDERGROUND
+// everything under the surface used to be plain stone. strata swap the stone for other blocks
+// depending on depth, and ore veins are carved out of the strata with 3D noise.
+
+// Stratum, a layer of rock that replaces stone below a certain height.
+type Stratum struct {
+	Block  string  // block the layer is made of
+	Below  int     // the layer starts at or below this z
+	Wobble float64 // how many voxels the top of the layer moves up and down
+}
+
+// OreVein, describes how an ore is spread through the underground.
+type OreVein struct {
+	Block     string  // ore block
+	MinZ      int     // lowest z the ore can appear at
+	MaxZ      int     // highest z the ore can appear at
+	Scale     float64 // noise frequency, higher is smaller veins
+	Frequency float64 // roughly how much of the rock in range becomes ore, 0 to 1
+}
+
+// layers from top to bottom, the first matching layer wins
+var undergroundStrata = []Stratum{
+	{Block: "Slate", Below: 6, Wobble: 2},
+}
+
+// blocks that ores are allowed to replace
+var oreHostBlocks = []string{"Stone", "Slate"}
+
+// ore veins, checked in order so rarer ores should go first
+var oreVeins = []OreVein{
+	{Block: "Gold_Ore", MinZ: 1, MaxZ: 10, Scale: .3, Frequency: .04},
+	{Block: "Iron_Ore", MinZ: 1, MaxZ: 20, Scale: .25, Frequency: .07},
+	{Block: "Coal_Ore", MinZ: 3, MaxZ: 40, Scale: .2, Frequency: .1},
+}
+
+// make the noise field that ores and strata are picked from
+func newOreNoise(seed int64) *perlin.Perlin {
+	return perlin.NewPerlin(2, 2, 2, seed+2)
+}
+
+// turn an ore frequency into a noise threshold.
+// perlin noise rarely leaves [-.6, .6], so a frequency of 0 never places anything.
+func oreThreshold(frequency float64) float64 {
+	return .6 * (1 - math.Max(0, math.Min(1, frequency)))
+}
+
+// get the rock block at a global position, before ores are placed
+func (world *World) getStratumBlock(globalX, globalY, z int) string {
+	for i, stratum := range undergroundStrata {
+		top := float64(stratum.Below)
+		if world.OreNoise != nil && stratum.Wobble != 0 {
+			top += world.OreNoise.Noise2D(float64(globalX)*.05+float64(i)*100, float64(globalY)*.05) * stratum.Wobble * 2
+		}
+		if float64(z) <= top {
+			return stratum.Block
+		}
+	}
+	return "Stone"
+}
+
+// get the ore at a global position, if there is one
+func (world *World) getOreBlock(globalX, globalY, z int) (ore string, found bool) {
+	if world.OreNoise == nil {
+		return "", false
+	}
+	for i, vein := range oreVeins {
+		if z < vein.MinZ || z > vein.MaxZ {
+			continue
+		}
+		// offset every ore so their veins don't overlap
+		offset := float64(i+1) * 1000
+		noiseValue := world.OreNoise.Noise3D(float64(globalX)*vein.Scale+offset, float64(globalY)*vein.Scale+offset, float64(z)*vein.Scale)
+		if noiseValue > oreThreshold(vein.Frequency) {
+			return vein.Block, true
+		}
+	}
+	return "", false
+}
+
+// get the underground block at a global position
+func (world *World) getUndergroundBlock(globalX, globalY, z int) string {
+	block := world.getStratumBlock(globalX, globalY, z)
+	if contains(oreHostBlocks, block) {
+		if ore, found := world.getOreBlock(globalX, globalY, z); found {
+			return ore
+		}
+	}
+	return block
+}
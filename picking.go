@@ -0,0 +1,95 @@
+package main
+
+import "math"
+
+// PICKING
+// works out which voxel is under a point on the screen, by following the line of voxels that all draw
+// to that point from the front of the world to the back.
+
+// voxels the cursor goes straight through
+var pickTransparent = []string{"Air", "Water"}
+
+// where a point in the world is drawn, relative to where voxel 0, 0, 0 is drawn.
+// voxels are centered on whole numbers, so the middle of the top face of voxel 0, 0, 0 is 0, 0, .5.
+func worldToScreen(x, y, z float64, direction [4]int) (screenX, screenY float64) {
+	sxX, sxY, syX, syY := float64(direction[0]), float64(direction[1]), float64(direction[2]), float64(direction[3])
+	screenX = (x*sxX+y*syX)*float64(v)/2 + float64(v)/2
+	screenY = (x*sxY+y*syY)*float64(v)/4 - z*float64(v)/2 + float64(v)/2
+	return
+}
+
+// how far a movement along the ground moves across the screen, the other way to screenToWorldMovement
+func worldToScreenMovement(direction [4]int, x, y float32) (screenX, screenY float32) {
+	toX, toY := worldToScreen(float64(x), float64(y), 0, direction)
+	fromX, fromY := worldToScreen(0, 0, 0, direction)
+	return float32(toX - fromX), float32(toY - fromY)
+}
+
+// is a point in the world hidden behind a voxel, from the camera's point of view.
+// voxels above topZ don't count, see pickVoxel.
+func (world *World) pointHidden(x, y, z float64, direction [4]int, topZ int) bool {
+	screenX, screenY := worldToScreen(x, y, z, direction)
+	hit, _, ok := world.pickVoxel(screenX, screenY, 0, 0, direction, topZ)
+	// the line of voxels goes down as it goes away from the camera, so anything hit above the point is in front of it
+	return ok && float64(hit[2])-.5 > z
+}
+
+// get the voxel under a screen position. originX and originY are where voxel 0, 0, 0 is drawn.
+// before is the voxel in front of the face that was hit, where a placed block would go.
+// voxels above topZ are cut away and the cursor goes straight through them, pass world.ChunkDepth to keep them all.
+func (world *World) pickVoxel(screenX, screenY, originX, originY float64, direction [4]int, topZ int) (hit, before [3]int, ok bool) {
+	sxX, sxY, syX, syY := float64(direction[0]), float64(direction[1]), float64(direction[2]), float64(direction[3])
+	det := sxX*syY - syX*sxY
+
+	// voxel x, y, z is drawn with the center of its top face at
+	// origin + ((x*sxX + y*syX)*v/2 + v/2, (x*sxY + y*syY)*v/4 - (z + .5)*v/2 + v/2).
+	// undo that at the top of the world to get where the line of voxels starts.
+	startZ := float64(world.ChunkDepth) + .5
+	a := (screenX - originX - float64(v)/2) / (float64(v) / 2)
+	b := (screenY - originY - float64(v)/2 + startZ*float64(v)/2) / (float64(v) / 4)
+	position := [3]float64{(syY*a - syX*b) / det, (sxX*b - sxY*a) / det, startZ}
+
+	// moving one voxel along x and y in this direction moves two quarter tiles down the screen,
+	// which is the same as moving one voxel down in z, so it keeps drawing to the same point
+	step := [3]float64{(syX * 2) / det, (-sxX * 2) / det, -1}
+
+	// walk the voxels along the line, one face at a time
+	var voxel, stepSign [3]int
+	var tMax, tDelta [3]float64
+	for axis := 0; axis < 3; axis++ {
+		// voxels are centered on whole numbers
+		shifted := position[axis] + .5
+		voxel[axis] = int(math.Floor(shifted))
+		tDelta[axis] = 1 / math.Abs(step[axis])
+		if step[axis] > 0 {
+			stepSign[axis] = 1
+			tMax[axis] = (math.Floor(shifted) + 1 - shifted) * tDelta[axis]
+		} else {
+			stepSign[axis] = -1
+			tMax[axis] = (shifted - math.Floor(shifted)) * tDelta[axis]
+		}
+	}
+
+	before = voxel
+	for steps := 0; steps < world.ChunkDepth*3+3 && voxel[2] >= 0; steps++ {
+		if voxel[2] < world.ChunkDepth && voxel[2] <= topZ {
+			found, loaded := world.GetVoxel(voxel[0], voxel[1], voxel[2])
+			if loaded && !contains(pickTransparent, found.Name) {
+				return voxel, before, true
+			}
+		}
+
+		// step across whichever face is closest
+		axis := 0
+		if tMax[1] < tMax[axis] {
+			axis = 1
+		}
+		if tMax[2] < tMax[axis] {
+			axis = 2
+		}
+		before = voxel
+		voxel[axis] += stepSign[axis]
+		tMax[axis] += tDelta[axis]
+	}
+	return hit, before, false
+}
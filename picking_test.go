@@ -0,0 +1,105 @@
+package main
+
+import "testing"
+
+// a world with one chunk that is flat stone up to floorZ
+func flatTestWorld(floorZ int) World {
+	world := World{ChunkSize: 32, ChunkDepth: 64, Chunks: make(map[[2]int]Chunk)}
+	chunk := Chunk{Voxels: make([]VoxelPointer, 32*32*64), Width: 32, Height: 32, Depth: 64}
+	for x := 0; x < chunk.Width; x++ {
+		for y := 0; y < chunk.Height; y++ {
+			for z := 0; z < chunk.Depth; z++ {
+				name := "Air"
+				if z <= floorZ {
+					name = "Stone"
+				}
+				chunk.SetVoxel(x, y, z, defaultVoxelDictionary.GetVoxelPointerTo(name))
+			}
+		}
+	}
+	world.Chunks[[2]int{0, 0}] = chunk
+	return world
+}
+
+func TestPickVoxel(t *testing.T) {
+	originX, originY := 100.0, -200.0
+
+	// which way along x and y is towards the camera
+	towards := map[[4]int][2]int{SOUTH: {1, 1}, WEST: {1, -1}, NORTH: {-1, -1}, EAST: {-1, 1}}
+
+	for _, direction := range [][4]int{SOUTH, WEST, NORTH, EAST} {
+		world := flatTestWorld(10)
+		target := [3]int{15, 15, 10}
+
+		// the middle of the top face of the target
+		screenX, screenY := getScreenPosition(target[0], target[1], target[2], float32(originX), float32(originY), 0, direction)
+		pickX, pickY := float64(screenX+tileWidth/2)+.1, float64(screenY+tileHeight/4)+.1
+
+		hit, before, ok := world.pickVoxel(pickX, pickY, originX, originY, direction, world.ChunkDepth)
+		if !ok || hit != target {
+			t.Errorf("%s: picked %v (%v), expected %v", directionName(direction), hit, ok, target)
+		}
+		if before != [3]int{target[0], target[1], target[2] + 1} {
+			t.Errorf("%s: before = %v, expected the voxel above %v", directionName(direction), before, target)
+		}
+
+		// a block in front of the target hides it
+		step := towards[direction]
+		blocker := [3]int{target[0] + 2*step[0], target[1] + 2*step[1], target[2] + 2}
+		world.SetVoxel(blocker[0], blocker[1], blocker[2], defaultVoxelDictionary.GetVoxelPointerTo("Cobblestone"))
+		hit, _, ok = world.pickVoxel(pickX, pickY, originX, originY, direction, world.ChunkDepth)
+		if !ok || hit != blocker {
+			t.Errorf("%s: picked %v (%v) behind a block, expected the block at %v", directionName(direction), hit, ok, blocker)
+		}
+	}
+}
+
+func TestWorldToScreen(t *testing.T) {
+	// the middle of the top face of a voxel is half a tile across and a quarter down from where the tile is drawn
+	for _, direction := range [][4]int{SOUTH, WEST, NORTH, EAST} {
+		tileX, tileY := getScreenPosition(3, -7, 12, 0, 0, 0, direction)
+		screenX, screenY := worldToScreen(3, -7, 12.5, direction)
+		if screenX != float64(tileX+tileWidth/2) || screenY != float64(tileY+tileHeight/4) {
+			t.Errorf("%s: top of the voxel at %g, %g, expected %d, %d", directionName(direction), screenX, screenY, tileX+tileWidth/2, tileY+tileHeight/4)
+		}
+	}
+}
+
+func TestPointHidden(t *testing.T) {
+	towards := map[[4]int][2]int{SOUTH: {1, 1}, WEST: {1, -1}, NORTH: {-1, -1}, EAST: {-1, 1}}
+
+	for _, direction := range [][4]int{SOUTH, WEST, NORTH, EAST} {
+		// standing on the floor
+		world := flatTestWorld(10)
+		if world.pointHidden(15, 15, 11, direction, world.ChunkDepth) {
+			t.Errorf("%s: a point on the floor is hidden", directionName(direction))
+		}
+
+		// a wall in front
+		step := towards[direction]
+		for z := 11; z <= 14; z++ {
+			world.SetVoxel(15+step[0], 15+step[1], z, defaultVoxelDictionary.GetVoxelPointerTo("Cobblestone"))
+		}
+		if !world.pointHidden(15, 15, 11, direction, world.ChunkDepth) {
+			t.Errorf("%s: a point behind a wall isn't hidden", directionName(direction))
+		}
+
+		// cutting the wall away shows the point, and the cursor goes through to the floor
+		if world.pointHidden(15, 15, 11, direction, 10) {
+			t.Errorf("%s: a point behind a cut away wall is hidden", directionName(direction))
+		}
+		screenX, screenY := worldToScreen(15, 15, 10.5, direction)
+		if hit, _, ok := world.pickVoxel(screenX, screenY, 0, 0, direction, 10); !ok || hit != [3]int{15, 15, 10} {
+			t.Errorf("%s: picked %v (%v) through a cut away wall, expected the floor", directionName(direction), hit, ok)
+		}
+
+		// the same wall behind
+		world = flatTestWorld(10)
+		for z := 11; z <= 14; z++ {
+			world.SetVoxel(15-step[0], 15-step[1], z, defaultVoxelDictionary.GetVoxelPointerTo("Cobblestone"))
+		}
+		if world.pointHidden(15, 15, 11, direction, world.ChunkDepth) {
+			t.Errorf("%s: a point in front of a wall is hidden", directionName(direction))
+		}
+	}
+}
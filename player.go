@@ -2,30 +2,69 @@ package main
 
 import (
 	"image"
+	"log"
+	"math"
+	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/colorm"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
 )
 
 // player texture atlas
 var playerTextureAtlas, _, _ = ebitenutil.NewImageFromFile("assets/player_atlas.png")
 
-// player texture map
+// player texture map, used when there's no animation
 // player sprites are 32x48 pixels.
 var playerTextureMap = map[string][4]int{
 	"Default": {0, 0, 32, 48},
 }
 
+const playerSpriteWidth, playerSpriteHeight = 32, 48
+
+// player animations, from the aseprite file the atlas is exported from. see animation.go
+var playerAnimations = loadPlayerAnimations("assets/player_atlas.aseprite")
+
+// how fast the player has to be going to be walking, or jumping
+const (
+	playerWalkSpeed = .01
+	playerJumpSpeed = .02
+)
+
 // Player, contains information about a player.
 type Player struct {
 	Position Vec3
 	Velocity Vec3
 	Drag     Vec3
 	Texture  string
+
+	Facing        string        // which way the sprite faces, see facings
+	Animation     string        // idle, walk, jump or swim
+	AnimationTime time.Duration // how long the animation has been playing
+}
+
+// load the player animations, without any if the file can't be read
+func loadPlayerAnimations(path string) AnimationSet {
+	file, err := readAseprite(path)
+	if err != nil {
+		log.Printf("ERROR: Failed to load player animations: %v", err)
+		return AnimationSet{}
+	}
+	return animationsFromAseprite(file)
 }
 
 var Gravity float32 = 0.01
 
+// make a player standing at a position
+func newPlayer(position Vec3) Player {
+	return Player{
+		Position: position,
+		Drag:     Vec3{.9, .9, .9},
+		Texture:  "Default",
+		Facing:   "south",
+	}
+}
+
 func (player *Player) Update(world World) {
 	// drag
 	player.Velocity.X *= player.Drag.X
@@ -41,19 +80,88 @@ func (player *Player) Update(world World) {
 	//player.Velocity.Z -= Gravity
 }
 
-// get screen position of the player regardless of camera position
-func (player *Player) getScreenPosition(depthShake float32) (screenX, screenY float32) {
-	screenX = ((player.Position.X - player.Position.Y) * float32(tileWidth) / 2) + float32(tileWidth/2)
-	screenY = ((player.Position.X+player.Position.Y)*float32(tileHeight)/4 - (player.Position.Z * float32(tileHeight) / 2)) + float32(tileHeight/2)
-	return
+// pick the animation for what the player is doing, and face the way they're moving on the screen
+func (player *Player) Animate(world World, direction [4]int, tick time.Duration) {
+	velocity := player.Velocity
+	if math.Hypot(float64(velocity.X), float64(velocity.Y)) > playerWalkSpeed {
+		player.Facing = facingFromScreen(worldToScreenMovement(direction, velocity.X, velocity.Y))
+	}
+
+	animation := "idle"
+	cell := player.Cell()
+	voxel, loaded := world.GetVoxel(cell[0], cell[1], cell[2])
+	switch {
+	case loaded && voxel.Name == "Water":
+		animation = "swim"
+	case math.Abs(float64(velocity.Z)) > playerJumpSpeed:
+		animation = "jump"
+	case math.Hypot(float64(velocity.X), float64(velocity.Y)) > playerWalkSpeed:
+		animation = "walk"
+	}
+
+	if animation != player.Animation {
+		player.Animation, player.AnimationTime = animation, 0
+	} else {
+		player.AnimationTime += tick
+	}
+}
+
+// where in the atlas the player's sprite is this frame
+func (player *Player) textureRect() [4]int {
+	if animation, ok := playerAnimations.Find(player.Animation, player.Facing); ok {
+		return atlasFrameRect(animation.FrameAt(player.AnimationTime), playerSpriteWidth, playerSpriteHeight, playerTextureAtlas.Bounds().Dx())
+	}
+	return playerTextureMap[player.Texture]
+}
+
+// the voxel the player's feet are in. voxels are centered on whole numbers.
+func (player *Player) Cell() [3]int {
+	return [3]int{
+		int(math.Floor(float64(player.Position.X) + .5)),
+		int(math.Floor(float64(player.Position.Y) + .5)),
+		int(math.Floor(float64(player.Position.Z) + .5)),
+	}
+}
+
+// where the player's feet are drawn, relative to where voxel 0, 0, 0 is drawn
+func (player *Player) screenPosition(direction [4]int) (screenX, screenY float64) {
+	return worldToScreen(float64(player.Position.X), float64(player.Position.Y), float64(player.Position.Z), direction)
+}
+
+// is any of the player hidden behind the world, not counting anything above topZ
+func (player *Player) Hidden(world *World, direction [4]int, topZ int) bool {
+	x, y, z := float64(player.Position.X), float64(player.Position.Y), float64(player.Position.Z)
+	// check the feet, the middle and the head
+	height := float64(playerSpriteHeight) / float64(v/2) * .9
+	for _, offset := range []float64{.1, height / 2, height} {
+		if world.pointHidden(x, y, z+offset, direction, topZ) {
+			return true
+		}
+	}
+	return false
 }
 
-func (player *Player) Render(screen *ebiten.Image, depthShake float32) {
+// draw the player standing where they are, with voxel 0, 0, 0 drawn at originX, originY.
+// the silhouette is a faint outline drawn on top of everything when they're behind something.
+func (player *Player) Render(screen *ebiten.Image, originX, originY float32, direction [4]int, silhouette bool) {
 	// get the texture
-	var texture = playerTextureAtlas.SubImage(image.Rect(playerTextureMap[player.Texture][0], playerTextureMap[player.Texture][1], playerTextureMap[player.Texture][2], playerTextureMap[player.Texture][3])).(*ebiten.Image)
+	rect := player.textureRect()
+	var texture = playerTextureAtlas.SubImage(image.Rect(rect[0], rect[1], rect[2], rect[3])).(*ebiten.Image)
 
-	// render
+	// render, with the bottom middle of the sprite at the player's feet
+	feetX, feetY := player.screenPosition(direction)
+	x, y := math.Round(feetX+float64(originX)-float64(playerSpriteWidth)/2), math.Round(feetY+float64(originY)-float64(playerSpriteHeight))
+	if silhouette {
+		// flat white, see through
+		var colorM colorm.ColorM
+		colorM.Scale(0, 0, 0, .4)
+		colorM.Translate(1, 1, 1, 0)
+		op := &colorm.DrawImageOptions{}
+		op.GeoM.Translate(x, y)
+		colorm.DrawImage(screen, texture, colorM, op)
+		return
+	}
 	op := &ebiten.DrawImageOptions{}
-	op.GeoM.Translate(float64(screen.Bounds().Dx()/2), float64(screen.Bounds().Dy()/2))
+	op.GeoM.Translate(x, y)
 	screen.DrawImage(texture, op)
 }
@@ -3,6 +3,7 @@ package main
 import (
 	"math"
 	"math/rand"
+	"sync"
 
 	"github.com/aquilax/go-perlin"
 )
@@ -19,7 +20,7 @@ const (
 	BiomeMountain
 )
 
-var biomes []Biome = []Biome{BiomePlains, BiomeForest, BiomeDesert, BiomeSnowy /*BiomeMountain*/}
+var biomes []Biome = []Biome{BiomePlains, BiomeForest, BiomeDesert, BiomeSnowy, BiomeMountain}
 
 type VoronoiPoint struct {
 	ChunkX, ChunkY int
@@ -103,6 +104,192 @@ func getBiome(chunkX, chunkY, localX, localY int) *Biome {
 	return getNearestVoronoiPoint(chunkX, chunkY, localX, localY).Biome
 }
 
+// BiomeHeightProfile, describes the shape of the terrain in a biome.
+// Heights are relative to World.SurfaceFeaturesBeginAt.
+type BiomeHeightProfile struct {
+	BaseHeight float64        // height offset of the biome
+	Amplitude  float64        // how much the noise moves the terrain up and down
+	Octaves    []HeightOctave // noise layers that are summed together
+}
+
+// HeightOctave, a single noise layer of a height profile.
+type HeightOctave struct {
+	Scale  float64 // noise frequency
+	Weight float64 // contribution to the final value
+}
+
+// height curves for each biome
+var biomeHeightProfiles = map[Biome]BiomeHeightProfile{
+	BiomePlains: {
+		BaseHeight: 0,
+		Amplitude:  8,
+		Octaves:    []HeightOctave{{Scale: .02, Weight: 1}},
+	},
+	BiomeForest: {
+		BaseHeight: 1,
+		Amplitude:  11,
+		Octaves:    []HeightOctave{{Scale: .02, Weight: 1}, {Scale: .07, Weight: .25}},
+	},
+	BiomeDesert: {
+		BaseHeight: 1,
+		Amplitude:  4,
+		Octaves:    []HeightOctave{{Scale: .015, Weight: 1}},
+	},
+	BiomeSnowy: {
+		BaseHeight: 3,
+		Amplitude:  13,
+		Octaves:    []HeightOctave{{Scale: .02, Weight: 1}, {Scale: .06, Weight: .3}},
+	},
+	BiomeMountain: {
+		BaseHeight: 8,
+		Amplitude:  30,
+		Octaves:    []HeightOctave{{Scale: .02, Weight: 1}, {Scale: .05, Weight: .5}, {Scale: .12, Weight: .15}},
+	},
+}
+
+// how far (in voxels) biome heights are blended across borders
+var biomeBlendRadius = 6
+
+// how much steeper terrain gets once it dips under the water level
+var underwaterSteepness float64 = 2
+
+// get the biome at a global voxel position
+func getBiomeAt(globalX, globalY int) *Biome {
+	chunkX, chunkY := floorDiv(globalX, 32), floorDiv(globalY, 32)
+	return getBiome(chunkX, chunkY, globalX-chunkX*32, globalY-chunkY*32)
+}
+
+// sample the height profile of a single biome at a global position
+func (world *World) biomeHeight(biome Biome, globalX, globalY int) float64 {
+	profile, ok := biomeHeightProfiles[biome]
+	if !ok {
+		profile = biomeHeightProfiles[BiomePlains]
+	}
+
+	var noiseValue, totalWeight float64
+	for i, octave := range profile.Octaves {
+		// offset each octave so they don't line up with each other
+		offset := float64(i) * 1000
+		noiseValue += world.PerlinNoise.Noise2D(float64(globalX)*octave.Scale+offset, float64(globalY)*octave.Scale+offset) * octave.Weight
+		totalWeight += octave.Weight
+	}
+	if totalWeight > 0 {
+		noiseValue /= totalWeight
+	}
+
+	return profile.BaseHeight + noiseValue*profile.Amplitude
+}
+
+// BiomeArea, the biomes of a rectangle of columns, looked up once so nearby columns can share them.
+type BiomeArea struct {
+	MinX, MinY    int
+	Width, Height int
+	Biomes        []*Biome
+}
+
+// look up the biomes of every column in a rectangle
+func newBiomeArea(minX, minY, width, height int) *BiomeArea {
+	area := &BiomeArea{MinX: minX, MinY: minY, Width: width, Height: height, Biomes: make([]*Biome, width*height)}
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			area.Biomes[y*width+x] = getBiomeAt(minX+x, minY+y)
+		}
+	}
+	return area
+}
+
+// get the biome at a global position, from the area if it's inside it.
+// a nil area looks every biome up.
+func (area *BiomeArea) biomeAt(globalX, globalY int) *Biome {
+	if area != nil {
+		x, y := globalX-area.MinX, globalY-area.MinY
+		if x >= 0 && y >= 0 && x < area.Width && y < area.Height {
+			return area.Biomes[y*area.Width+x]
+		}
+	}
+	return getBiomeAt(globalX, globalY)
+}
+
+// get the terrain height at a global position, relative to SurfaceFeaturesBeginAt.
+// heights are blended between neighboring biomes so borders stay smooth.
+func (world *World) getTerrainHeight(globalX, globalY int) int {
+	return world.terrainHeightIn(nil, globalX, globalY)
+}
+
+// get the terrain height at a global position, with the biomes around it from an area
+func (world *World) terrainHeightIn(area *BiomeArea, globalX, globalY int) int {
+	// count the biomes in a square around the column
+	type biomeCount struct {
+		biome Biome
+		count int
+	}
+	counts := make([]biomeCount, 0, len(biomes))
+	var samples int
+	step := max(1, biomeBlendRadius/2)
+	for dx := -biomeBlendRadius; dx <= biomeBlendRadius; dx += step {
+		for dy := -biomeBlendRadius; dy <= biomeBlendRadius; dy += step {
+			biome := *area.biomeAt(globalX+dx, globalY+dy)
+			i := 0
+			for i < len(counts) && counts[i].biome != biome {
+				i++
+			}
+			if i == len(counts) {
+				counts = append(counts, biomeCount{biome: biome})
+			}
+			counts[i].count++
+			samples++
+		}
+	}
+
+	// and average their heights, so each biome's noise is only sampled once
+	var height float64
+	for _, biome := range counts {
+		height += world.biomeHeight(biome.biome, globalX, globalY) * float64(biome.count)
+	}
+	height /= float64(samples)
+
+	// makes underwater topography steeper, so shorelines stay shallow but oceans get deep
+	waterHeight := float64(world.WaterLevel - world.SurfaceFeaturesBeginAt - 2)
+	if height < waterHeight {
+		height = waterHeight - (waterHeight-height)*underwaterSteepness
+	}
+
+	// keep the terrain inside the chunk, with some room for trees on top
+	return clampi(int(math.Floor(height)), -world.SurfaceFeaturesBeginAt+1, world.ChunkDepth-world.SurfaceFeaturesBeginAt-12)
+}
+
+// get the block that sits on top of the terrain in a biome
+func surfaceBlock(biome Biome, riverBank bool) string {
+	// river banks are sandy
+	if riverBank {
+		return "Sand"
+	}
+
+	switch biome {
+	case BiomeSnowy:
+		return "Snowy_Grass"
+	case BiomeMountain:
+		return "Stone"
+	case BiomeDesert:
+		return "Sand"
+	default:
+		return "Grass"
+	}
+}
+
+// get the z and name of the top terrain block at a global position, without generating the chunk.
+// underwater columns return "Water".
+func (world *World) getSurface(globalX, globalY int) (z int, block string) {
+	height := world.getTerrainHeight(globalX, globalY)
+	height, riverWater, riverBank := world.carveRiver(globalX, globalY, height)
+
+	z = world.SurfaceFeaturesBeginAt + height + 2
+	if z < world.WaterLevel || riverWater > height {
+		return z, "Water"
+	}
+	return z, surfaceBlock(*getBiomeAt(globalX, globalY), riverBank)
+}
+
 // idk why this is here
 // func pseudoRandomTangent(x float64) float64 {
 // 	return math.Tan(x*12.9898) - math.Floor(math.Tan(x*12.9898))
@@ -111,16 +298,21 @@ func getBiome(chunkX, chunkY, localX, localY int) *Biome {
 // initialize a world with things like random seed and perlin noise
 func (world *World) Initialize(seed int64) {
 	world.Seed = seed
+	// use the seed for the noise parameters too, so the same seed always makes the same terrain
+	srand := rand.New(rand.NewSource(seed))
 	world.PerlinNoise = perlin.NewPerlin(
-		float64(50+rand.Intn(20))/100, // Persistence
-		float64(rand.Intn(50))/100,    // Lacunarity
-		3,                             // Octaves
-		world.Seed,                    // Seed
+		float64(50+srand.Intn(20))/100, // Persistence
+		float64(srand.Intn(50))/100,    // Lacunarity
+		3,                              // Octaves
+		world.Seed,                     // Seed
 	)
+	world.RiverNoise = newRiverNoise(world.Seed)
+	world.OreNoise = newOreNoise(world.Seed)
 	world.SurfaceFeaturesBeginAt = 10
 	world.WaterLevel = 5 + world.SurfaceFeaturesBeginAt
 
 	world.Chunks = make(map[[2]int]Chunk)
+	world.ChunksMutex = &sync.RWMutex{}
 	world.ChunkSize = 32
 	world.ChunkDepth = 64
 }
@@ -140,35 +332,34 @@ func (world *World) generateChunk(position [2]int, chunkWidth, chunkHeight, chun
 	}
 	chunk := MakeChunk(chunkArray)
 
+	// the biomes every column in the chunk blends its height from
+	area := newBiomeArea(position[0]*chunkWidth-biomeBlendRadius, position[1]*chunkHeight-biomeBlendRadius, chunkWidth+biomeBlendRadius*2, chunkHeight+biomeBlendRadius*2)
+
 	// procedurally generate voxels
 	for x := 0; x < chunkWidth; x++ {
 		for y := 0; y < chunkHeight; y++ {
-			for z := 0; z < chunkDepth; z++ {
+			// get the terrain height at this column
+			height := world.terrainHeightIn(area, position[0]*chunkWidth+x, position[1]*chunkHeight+y)
 
-				// get the noise value at this position
-				var scale float64 = .02
-				noiseValue := world.PerlinNoise.Noise2D(float64(position[0]*chunkWidth+x)*scale, float64(position[1]*chunkHeight+y)*scale) * 10
-				// FIXME: the idea is good but something is broken here
-				// if z < world.WaterLevel { // makes underwater topography steeper
-				// 	noiseValue *= 2
-				// }
-				// if z > 30 { // "mountains"
-				// 	noiseValue *= 2
-				// }
+			// carve rivers
+			height, riverWater, riverBank := world.carveRiver(position[0]*chunkWidth+x, position[1]*chunkHeight+y, height)
 
-				// get the biome at this position
-				biome := getBiome(position[0], position[1], x, y)
+			// get the biome at this position
+			biome := getBiome(position[0], position[1], x, y)
+
+			for z := 0; z < chunkDepth; z++ {
 
 				// set to air by default
 				chunk.SetVoxel(x, y, z, defaultVoxelDictionary.GetVoxelPointerTo("Air"))
 
 				// fill with water up to the water level
-				if z <= world.WaterLevel {
+				// and rivers up to their own level, which is above it on high ground
+				if z <= world.WaterLevel || z <= world.SurfaceFeaturesBeginAt+riverWater+2 {
 					chunk.SetVoxel(x, y, z, defaultVoxelDictionary.GetVoxelPointerTo("Water"))
 				}
 
-				// fill with dirt/sand up noise value
-				if z <= world.SurfaceFeaturesBeginAt+int(noiseValue)+2 {
+				// fill with dirt/sand up to the terrain height
+				if z <= world.SurfaceFeaturesBeginAt+height+2 {
 					var dirtBlock string
 
 					switch *biome {
@@ -184,26 +375,13 @@ func (world *World) generateChunk(position [2]int, chunkWidth, chunkHeight, chun
 				}
 
 				// fill with grass
-				if z == world.SurfaceFeaturesBeginAt+int(noiseValue)+2 && world.SurfaceFeaturesBeginAt+int(noiseValue)+2 >= world.WaterLevel {
-					var grassBlock string
-
-					switch *biome {
-					case BiomeSnowy:
-						grassBlock = "Snowy_Grass"
-					case BiomeMountain:
-						grassBlock = "Stone"
-					case BiomeDesert:
-						grassBlock = "Sand"
-					default:
-						grassBlock = "Grass"
-					}
-
-					chunk.SetVoxel(x, y, z, defaultVoxelDictionary.GetVoxelPointerTo(grassBlock))
+				if z == world.SurfaceFeaturesBeginAt+height+2 && world.SurfaceFeaturesBeginAt+height+2 >= world.WaterLevel {
+					chunk.SetVoxel(x, y, z, defaultVoxelDictionary.GetVoxelPointerTo(surfaceBlock(*biome, riverBank)))
 				}
 
-				// fill with stone up to a point
-				if z <= world.SurfaceFeaturesBeginAt+int(noiseValue)-1 || z <= world.SurfaceFeaturesBeginAt-(2+int(noiseValue/10)) {
-					chunk.SetVoxel(x, y, z, VoxelPointer{VoxelDictionary: &VDict, Index: 4})
+				// fill with stone (and whatever else is underground) up to a point
+				if z <= world.SurfaceFeaturesBeginAt+height-1 || z <= world.SurfaceFeaturesBeginAt-(2+height/10) {
+					chunk.SetVoxel(x, y, z, defaultVoxelDictionary.GetVoxelPointerTo(world.getUndergroundBlock(position[0]*chunkWidth+x, position[1]*chunkHeight+y, z)))
 				}
 
 				// ocean sand
@@ -217,6 +395,9 @@ func (world *World) generateChunk(position [2]int, chunkWidth, chunkHeight, chun
 		}
 	}
 
+	// seed the rng on the chunk, so decorations come out the same every time the chunk is generated
+	crand := rand.New(rand.NewSource(int64(hashCoords(world.Seed, position[0], position[1], 0))))
+
 	// decorations
 	for x := 0; x < chunkWidth; x++ {
 		for y := 0; y < chunkHeight; y++ {
@@ -242,29 +423,23 @@ func (world *World) generateChunk(position [2]int, chunkWidth, chunkHeight, chun
 			}
 
 			// grass
-			if rand.Intn(2) == 0 && *biome != BiomeDesert && *biome != BiomeMountain {
-				if (*biome == BiomeSnowy && rand.Intn(2) == 0) || *biome != BiomeSnowy { // grass is rarer in snowy biomes
+			if crand.Intn(2) == 0 && *biome != BiomeDesert && *biome != BiomeMountain {
+				if (*biome == BiomeSnowy && crand.Intn(2) == 0) || *biome != BiomeSnowy { // grass is rarer in snowy biomes
 					chunk.PlaceDecoration(x, y, defaultVoxelDictionary.GetVoxelPointerTo(grassDecoBlock), defaultVoxelDictionary.GetVoxelPointerTo(grassBlock))
 				}
 			}
 
 			// flowers
-			if rand.Intn(5) == 0 && *biome != BiomeDesert && *biome != BiomeMountain && *biome != BiomeSnowy {
+			if crand.Intn(5) == 0 && *biome != BiomeDesert && *biome != BiomeMountain && *biome != BiomeSnowy {
 				chunk.PlaceDecoration(x, y, defaultVoxelDictionary.GetVoxelPointerTo(flowerBlock), defaultVoxelDictionary.GetVoxelPointerTo(grassBlock))
 			}
-
-			// trees
-			if rand.Intn(50) == 0 {
-				chunk.PlaceTree(x, y)
-			}
-
-			if rand.Intn(200) == 0 {
-				chunk.PlaceCactus(x, y)
-			}
 		}
 	}
 
-	world.Chunks[position] = chunk
+	// trees, rocks, buildings, etc.
+	world.placeStructures(&chunk, position)
+
+	world.storeChunk(position, chunk)
 }
 
 // "Drop" a decoration onto a given voxel at a given (x, y) position.
@@ -285,58 +460,3 @@ func (chunk *Chunk) PlaceDecoration(x, y int, decoration, placesOn VoxelPointer)
 	}
 	return
 }
-
-// place a tree at the given position
-func (chunk *Chunk) PlaceTree(x, y int) (placed bool) {
-	// move down until we hit a grass block that has have air above it
-	for z := chunk.Depth - 1; z >= 0; z-- {
-		if chunk.GetVoxel(x, y, z).Name == "Grass" || chunk.GetVoxel(x, y, z).Name == "Snowy_Grass" {
-			// check if there is air above it
-			if chunk.GetVoxel(x, y, z+1).Name != "Air" {
-				return false
-			}
-
-			// leaves motherfucka
-			leaves := "Leaves"
-			if chunk.GetVoxel(x, y, z).Name == "Snowy_Grass" {
-				leaves = "Snowy_Leaves"
-			}
-			for x2 := x - 1; x2 < x+2; x2++ {
-				for y2 := y - 1; y2 < y+2; y2++ {
-					chunk.SetVoxel(x2, y2, z+4, defaultVoxelDictionary.GetVoxelPointerTo(leaves))
-					if math.Abs(float64(x2-x)) != math.Abs(float64(y2-y)) {
-						chunk.SetVoxel(x2, y2, z+5, defaultVoxelDictionary.GetVoxelPointerTo(leaves))
-					}
-				}
-			}
-			chunk.SetVoxel(x, y, z+5, defaultVoxelDictionary.GetVoxelPointerTo(leaves)) // top middle leaf
-
-			// place the trunk
-			for z2 := z + 1; z2 <= z+4; z2++ {
-				chunk.SetVoxel(x, y, z2, defaultVoxelDictionary.GetVoxelPointerTo("Wood"))
-			}
-			return true
-		}
-	}
-	return false
-}
-
-// place cactus
-// place a tree at the given position
-func (chunk *Chunk) PlaceCactus(x, y int) (placed bool) {
-	// move down until we hit a sand block that has have air above it
-	for z := chunk.Depth - 1; z >= 0; z-- {
-		if chunk.GetVoxel(x, y, z).Name == "Sand" {
-			// check if there is air above it
-			if chunk.GetVoxel(x, y, z+1).Name != "Air" {
-				return false
-			}
-
-			// place the cactus body
-			chunk.SetVoxel(x, y, z+1, defaultVoxelDictionary.GetVoxelPointerTo("Cactus"))
-			chunk.SetVoxel(x, y, z+2, defaultVoxelDictionary.GetVoxelPointerTo("Cactus"))
-			chunk.SetVoxel(x, y, z+3, defaultVoxelDictionary.GetVoxelPointerTo("Cactus"))
-		}
-	}
-	return false
-}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestTerrainHeightIsContinuousAcrossBiomeBorders(t *testing.T) {
+	world := World{}
+	world.Initialize(42)
+
+	// step over the biome borders along a few rows. without blending, the height jumps by the
+	// difference between the two biomes' profiles. with it, a step moves at most one row of the
+	// 5x5 samples over the border, so a fifth of that, doubled underwater, plus the slope of the noise
+	borders := 0
+	for y := 0; y < 2000; y += 97 {
+		for x := 0; x < 2000; x++ {
+			left, right := *getBiomeAt(x, y), *getBiomeAt(x+1, y)
+			if left == right {
+				continue
+			}
+			borders++
+			jump := math.Abs(float64(world.getTerrainHeight(x+1, y) - world.getTerrainHeight(x, y)))
+			unblended := math.Abs(world.biomeHeight(right, x+1, y) - world.biomeHeight(left, x, y))
+			if limit := 2 + unblended*2/5; jump > limit {
+				t.Errorf("height jumps by %v from %d to %d at y %d, more than %.1f", jump, x, x+1, y, limit)
+			}
+		}
+	}
+	if borders == 0 {
+		t.Fatal("no biome borders found")
+	}
+}
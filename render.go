@@ -5,9 +5,11 @@ import (
 	"log"
 	"math"
 	"slices"
+	"strings"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
 )
 
 const (
@@ -62,53 +64,101 @@ func getScreenPosition(x, y, z int, cameraX, cameraY float32, depthShake float32
 	return
 }
 
-func (chunk *Chunk) VoxelIsVisible(x, y, z int) bool {
-	// check if voxel is in bounds
-	if x < 0 || y < 0 || z < 0 || x >= chunk.Width || y >= chunk.Height || z >= chunk.Depth {
-		return false
+// the camera directions, each one turned a quarter from the one before
+var directions = [][4]int{SOUTH, WEST, NORTH, EAST}
+
+// turn the camera a number of quarter turns
+func rotateDirection(direction [4]int, turns int) [4]int {
+	for i, d := range directions {
+		if d == direction {
+			return directions[((i+turns)%len(directions)+len(directions))%len(directions)]
+		}
 	}
+	return SOUTH
+}
 
-	var voxelDict = chunk.GetVoxelDictionary(x, y, z)
-	return slices.Contains(voxelDict.GetTransparentNames(), chunk.GetVoxel(x+1, y, z).Name) ||
-		slices.Contains(voxelDict.GetTransparentNames(), chunk.GetVoxel(x, y+1, z).Name) ||
-		slices.Contains(voxelDict.GetTransparentNames(), chunk.GetVoxel(x, y, z+1).Name) ||
-		// let it render if it's on the edge of the chunk
-		(x == 0 || x == chunk.Width-1 || y == 0 || y == chunk.Height-1 || z == 0 || z == chunk.Depth-1)
+// draw the outline of the top face of a tile
+func drawTileOutline(screen *ebiten.Image, x, y float32, clr color.Color) {
+	w, h := float32(tileWidth), float32(tileHeight)
+	corners := [][2]float32{{x + w/2, y}, {x + w, y + h/4}, {x + w/2, y + h/2}, {x, y + h/4}}
+	for i, corner := range corners {
+		next := corners[(i+1)%len(corners)]
+		vector.StrokeLine(screen, corner[0], corner[1], next[0], next[1], 1, clr, false)
+	}
 }
 
-func ChunkContainingGlobalPointVisibleInViewport(x, y, z int, cameraX, cameraY float32, depthShake float32, screenWidth, screenHeight int, direction [4]int) bool {
-	// get the voxel space origin of the chunk
-	var chunkX, chunkY, chunkZ int = (x / 32) * 32, (y / 32) * 32, (z / 32) * 32
+// where voxel 0, 0, 0 is drawn on the screen
+func (game *Game) renderOrigin() (x, y float32) {
+	return game.Camera[0], game.Camera[1]
+}
 
-	// get the screen space bounds (diamond vertices) of the chunk
-	diamondTopX, diamondTopY := getScreenPosition(chunkX, chunkY, chunkZ+32, cameraX, cameraY, depthShake, direction)
-	diamondBottomX, diamondBottomY := getScreenPosition(chunkX+32, chunkY+32, chunkZ, cameraX, cameraY, depthShake, direction)
-	diamondLeftX, diamondLeftY := getScreenPosition(chunkX, chunkY+32, chunkZ+32, cameraX, cameraY, depthShake, direction)
-	diamondRightX, diamondRightY := getScreenPosition(chunkX+32, chunkY, chunkZ+32, cameraX, cameraY, depthShake, direction)
+// the highest voxel drawn normally, everything above it is hidden or faded by the cut-away view.
+// the slice starts just above the player's head and moves up and down with CutawayOffset.
+func (game *Game) cutawayTop() int {
+	if !game.Cutaway {
+		return game.World.ChunkDepth
+	}
+	return game.Player.Cell()[2] + 1 + game.CutawayOffset
+}
 
-	// check if any lines of the diamond intersect the camera
-	// top to right
-	if doesLineIntersectRectangle(diamondTopX, diamondTopY, diamondRightX, diamondRightY, 0, 0, screenWidth, screenHeight) {
-		return true
+// get the names of the camera directions
+func directionName(direction [4]int) string {
+	switch direction {
+	case SOUTH:
+		return "South"
+	case WEST:
+		return "West"
+	case NORTH:
+		return "North"
+	case EAST:
+		return "East"
 	}
-	// right to bottom
-	if doesLineIntersectRectangle(diamondRightX, diamondRightY, diamondBottomX, diamondBottomY, 0, 0, screenWidth, screenHeight) {
-		return true
+	return "Unknown"
+}
+
+// get a camera direction from its name, case insensitive
+func directionFromName(name string) (direction [4]int, ok bool) {
+	for _, direction := range directions {
+		if strings.EqualFold(directionName(direction), name) {
+			return direction, true
+		}
 	}
-	// bottom to left
-	if doesLineIntersectRectangle(diamondBottomX, diamondBottomY, diamondLeftX, diamondLeftY, 0, 0, screenWidth, screenHeight) {
-		return true
+	return SOUTH, false
+}
+
+// get the direction voxels have to be walked along x and y to draw them back to front.
+// screen y grows with x*sxY + y*syY, so each axis is walked the way that moves down the screen.
+func renderStep(direction [4]int) (stepX, stepY int) {
+	return direction[1], direction[3]
+}
+
+// get where to start and stop walking an axis of a given size in the direction of step
+func renderRange(step, size int) (start, stop int) {
+	if step < 0 {
+		return size - 1, -1
 	}
-	// left to top
-	if doesLineIntersectRectangle(diamondLeftX, diamondLeftY, diamondTopX, diamondTopY, 0, 0, screenWidth, screenHeight) {
-		return true
+	return 0, size
+}
+
+// check if a voxel has a transparent neighbor on a side that faces the camera
+func (chunk *Chunk) VoxelIsVisible(x, y, z int, direction [4]int) bool {
+	// check if voxel is in bounds
+	if x < 0 || y < 0 || z < 0 || x >= chunk.Width || y >= chunk.Height || z >= chunk.Depth {
+		return false
 	}
 
-	// must not be in bounds
-	return false
+	stepX, stepY := renderStep(direction)
+	var voxelDict = chunk.GetVoxelDictionary(x, y, z)
+	return slices.Contains(voxelDict.GetTransparentNames(), chunk.GetVoxel(x+stepX, y, z).Name) ||
+		slices.Contains(voxelDict.GetTransparentNames(), chunk.GetVoxel(x, y+stepY, z).Name) ||
+		slices.Contains(voxelDict.GetTransparentNames(), chunk.GetVoxel(x, y, z+1).Name) ||
+		// let it render if it's on the edge of the chunk
+		(x == 0 || x == chunk.Width-1 || y == 0 || y == chunk.Height-1 || z == 0 || z == chunk.Depth-1)
 }
 
 // render a chunk with a given camera position
+// when renderPlayer is set the player is drawn in with the voxels, after everything behind and below them
+// and before everything in front of and above them.
 func (chunk Chunk) Render(screen *ebiten.Image, cameraX, cameraY float32, depthShake float32, game *Game, renderPlayer bool) (blocksRendered int) {
 	// create an offscreen buffer to render to
 	chunkRenderBuffer := ebiten.NewImage(screen.Bounds().Dx(), screen.Bounds().Dy())
@@ -123,33 +173,29 @@ func (chunk Chunk) Render(screen *ebiten.Image, cameraX, cameraY float32, depthS
 	screenHeight := screen.Bounds().Dy()
 
 	// get the proper render order based on camera direction
-	startX, stopX, stepX := 0, 0, 0
-	startY, stopY, stepY := 0, 0, 0
-	startZ, stopZ, stepZ := 0, 0, 0
-	switch game.Direction {
-	case SOUTH: // CORRECT
-		startX, stopX, stepX = 0, chunkWidth, 1
-		startY, stopY, stepY = 0, chunkHeight, 1
-		startZ, stopZ, stepZ = 0, chunkDepth, 1
-	case NORTH: // FIXME
-		startX, stopX, stepX = chunkWidth-1, 0, -1
-		startY, stopY, stepY = chunkHeight-1, 0, -1
-		startZ, stopZ, stepZ = 0, chunkDepth, 1
-	case EAST: // FIXME
-		startX, stopX, stepX = 0, chunkWidth, 1
-		startY, stopY, stepY = chunkHeight-1, 0, -1
-		startZ, stopZ, stepZ = 0, chunkDepth, 1
-	case WEST: // CORRECT
-		startX, stopX, stepX = 0, chunkWidth, 1
-		startY, stopY, stepY = chunkHeight-1, 0, -1
-		startZ, stopZ, stepZ = 0, chunkDepth, 1
-	}
+	stepX, stepY := renderStep(game.Direction)
+	startX, stopX := renderRange(stepX, chunkWidth)
+	startY, stopY := renderRange(stepY, chunkHeight)
+	startZ, stopZ, stepZ := 0, chunkDepth, 1
+
+	// the player's voxel, in this chunk
+	playerCell := game.Player.Cell()
+	playerX, playerY := floorMod(playerCell[0], chunkWidth), floorMod(playerCell[1], chunkHeight)
+	playerZ := clampi(playerCell[2], startZ, stopZ)
+	originX, originY := game.renderOrigin()
+	cutawayTop := game.cutawayTop()
+	cutawayFade := game.Settings.CutawayFade
 
 	blocksRendered = 0
 	// iterate through voxels
 	for x := startX; x != stopX; x += stepX {
 		for y := startY; y != stopY; y += stepY {
+			playerColumn := renderPlayer && x == playerX && y == playerY
 			for z := startZ; z != stopZ; z += stepZ {
+				if playerColumn && z == playerZ {
+					game.Player.Render(chunkRenderBuffer, originX, originY, game.Direction, false)
+				}
+
 				// // get the screen position
 				screenX, screenY := getScreenPosition(x, y, z, cameraX, cameraY, depthShake, game.Direction)
 
@@ -164,15 +210,26 @@ func (chunk Chunk) Render(screen *ebiten.Image, cameraX, cameraY float32, depthS
 					continue
 				}
 
-				// check if the voxel is even visible
-				if !chunk.VoxelIsVisible(x, y, z) {
+				// cut away above the slice
+				cutAway := z > cutawayTop
+				if cutAway && !cutawayFade {
+					continue
+				}
+
+				// x-ray only shows ores, but shows them even when they're buried
+				if game.XRayMode {
+					if !slices.Contains(voxelDict.Ores, currentVoxel.Name) {
+						continue
+					}
+				} else if !chunk.VoxelIsVisible(x, y, z, game.Direction) && z != cutawayTop {
+					// check if the voxel is even visible, the top of the cut-away slice always is
 					continue // Skip rendering this voxel
 				}
 
 				// hide any transparent under itself (only Transparent, not TransparentNoCull)
-				if slices.Contains(transparentNames, currentVoxel.Name) {
-					if chunk.GetVoxel(x+1, y, z).Name == currentVoxel.Name &&
-						chunk.GetVoxel(x, y+1, z).Name == currentVoxel.Name &&
+				if slices.Contains(transparentNames, currentVoxel.Name) && z != cutawayTop {
+					if chunk.GetVoxel(x+stepX, y, z).Name == currentVoxel.Name &&
+						chunk.GetVoxel(x, y+stepY, z).Name == currentVoxel.Name &&
 						chunk.GetVoxel(x, y, z+1).Name == currentVoxel.Name {
 						//!(x == 0 || x == chunk.Width-1 || y == 0 || y == chunk.Height-1 || z == 0 || z == chunk.Depth-1) { // old edge culling, no longer used
 						continue
@@ -192,10 +249,17 @@ func (chunk Chunk) Render(screen *ebiten.Image, cameraX, cameraY float32, depthS
 				// draw the texture
 				op := &ebiten.DrawImageOptions{}
 				op.GeoM.Translate(float64(screenX), float64(screenY))
+				if cutAway {
+					op.ColorScale.ScaleAlpha(.25)
+				}
 				chunkRenderBuffer.DrawImage(texture, op)
 
 				blocksRendered++
 			}
+			// above the top of the world
+			if playerColumn && playerZ == stopZ {
+				game.Player.Render(chunkRenderBuffer, originX, originY, game.Direction, false)
+			}
 		}
 	}
 
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"math"
+
+	"github.com/aquilax/go-perlin"
+)
+
+// RIVERS
+// rivers follow the zero line of a separate noise field ("ridge" noise).
+// since it only depends on the global position, every chunk agrees on where the rivers are,
+// no matter which one gets generated first.
+
+var (
+	riverScale      float64 = .006 // frequency of the river noise, lower is longer rivers
+	riverWidth      float64 = .025 // noise distance from the ridge that counts as river channel
+	riverBankWidth  float64 = .05  // noise distance from the ridge that counts as river bank
+	riverDepth      int     = 3    // how far under the water level the channel bottom is
+	riverMaxCarve   int     = 12   // deepest a river valley cuts into the terrain, so high ground gets a valley instead of being cut down to the sea
+	riverNoiseAlpha float64 = 2    // persistence of the river noise
+	riverNoiseBeta  float64 = 2    // lacunarity of the river noise
+)
+
+// make the noise field that rivers are carved from
+func newRiverNoise(seed int64) *perlin.Perlin {
+	return perlin.NewPerlin(riverNoiseAlpha, riverNoiseBeta, 2, seed+1)
+}
+
+// distance from the river ridge at a global position, 0 is the middle of the river
+func (world *World) riverDistance(globalX, globalY int) float64 {
+	if world.RiverNoise == nil {
+		return math.Inf(1)
+	}
+	return math.Abs(world.RiverNoise.Noise2D(float64(globalX)*riverScale, float64(globalY)*riverScale))
+}
+
+// carve a river into a terrain height (relative to SurfaceFeaturesBeginAt).
+// returns the new height, the height the river's water comes up to (the same as the new height where it's dry),
+// and whether the column is a sandy river bank.
+func (world *World) carveRiver(globalX, globalY, height int) (carvedHeight, waterHeight int, bank bool) {
+	distance := world.riverDistance(globalX, globalY)
+	if distance >= riverBankWidth {
+		return height, height, false
+	}
+
+	// the height where the surface block sits exactly at the water level.
+	// on high ground the river only cuts so deep, so it runs along the bottom of a valley above the sea
+	waterHeight = max(world.WaterLevel-world.SurfaceFeaturesBeginAt-2, height-riverMaxCarve)
+
+	if distance < riverWidth {
+		// channel, deepest in the middle and full of water
+		channelDepth := int(math.Round(float64(riverDepth) * (1 - distance/riverWidth)))
+		carvedHeight = min(height, waterHeight-1-channelDepth)
+		return carvedHeight, max(carvedHeight, waterHeight), true
+	}
+
+	// banks slope down towards the water
+	slope := (distance - riverWidth) / (riverBankWidth - riverWidth)
+	carvedHeight = min(height, waterHeight+int(math.Round(float64(height-waterHeight)*slope)))
+	return carvedHeight, carvedHeight, true
+}
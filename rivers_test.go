@@ -0,0 +1,65 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRiversCarveIntoHighGround(t *testing.T) {
+	world := World{}
+	world.Initialize(42)
+
+	// find the middle of a river
+	x := 0
+	for ; x < 10000 && world.riverDistance(x, 0) >= riverWidth/4; x++ {
+	}
+	if world.riverDistance(x, 0) >= riverWidth/4 {
+		t.Fatal("no river found")
+	}
+
+	waterHeight := world.WaterLevel - world.SurfaceFeaturesBeginAt - 2
+	if height, water, bank := world.carveRiver(x, 0, waterHeight+2); !bank || height >= waterHeight || water != waterHeight {
+		t.Errorf("low ground carved to %d with water at %d, expected under the water at %d", height, water, waterHeight)
+	}
+
+	// high ground gets a valley as deep as riverMaxCarve, not left alone, with the river running along the bottom
+	high := waterHeight + riverMaxCarve + 10
+	if height, water, bank := world.carveRiver(x, 0, high); !bank || water != high-riverMaxCarve || height >= water {
+		t.Errorf("high ground carved to %d with water at %d, expected water at %d above the channel", height, water, high-riverMaxCarve)
+	}
+}
+
+func TestRiverChannelsHoldWater(t *testing.T) {
+	world := World{}
+	world.Initialize(42)
+
+	// find the middle of a river on high ground
+	waterHeight := world.WaterLevel - world.SurfaceFeaturesBeginAt - 2
+	var x, y int
+	found := false
+	for i := 0; i < 200000 && !found; i++ {
+		x, y = i%1000*4, i/1000*4
+		found = world.riverDistance(x, y) < riverWidth/4 && world.getTerrainHeight(x, y) > waterHeight+riverMaxCarve
+	}
+	if !found {
+		t.Fatal("no river on high ground found")
+	}
+
+	position := [2]int{int(math.Floor(float64(x) / 32)), int(math.Floor(float64(y) / 32))}
+	world.generateChunk(position, 32, 32, 64, defaultVoxelDictionary)
+	chunk := world.Chunks[position]
+	localX, localY := x-position[0]*32, y-position[1]*32
+
+	height, water, _ := world.carveRiver(x, y, world.getTerrainHeight(x, y))
+	if water <= height || world.SurfaceFeaturesBeginAt+water+2 <= world.WaterLevel {
+		t.Fatalf("channel at %d, %d carved to %d with water at %d, expected water above the sea", x, y, height, water)
+	}
+	for z := world.SurfaceFeaturesBeginAt + height + 3; z <= world.SurfaceFeaturesBeginAt+water+2; z++ {
+		if name := chunk.GetVoxel(localX, localY, z).Name; name != "Water" {
+			t.Errorf("channel at %d, %d, %d is %s, expected water", x, y, z, name)
+		}
+	}
+	if z, block := world.getSurface(x, y); block != "Water" {
+		t.Errorf("surface of the channel at %d is %s, expected water", z, block)
+	}
+}
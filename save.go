@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -11,13 +12,29 @@ import (
 	"time"
 )
 
+// the save format version this build writes.
+// bump it and add a step to saveMigrations whenever WorldMetadata, PlayerJSON or the chunk format changes.
+const SaveFormatVersion = 1
+
 // json writable world metadata
 type WorldMetadata struct {
-	Seed     int64  `json:"seed"`
-	SavePath string `json:"save_path"`
+	FormatVersion int       `json:"format_version"`
+	Name          string    `json:"name"`
+	Seed          int64     `json:"seed"`
+	SavePath      string    `json:"save_path"`
+	LastPlayed    time.Time `json:"last_played"`
+}
+
+// json chunk, voxels are run-length encoded against a palette of voxel names
+type ChunkJSON struct {
+	Palette []string `json:"palette"`
+	Runs    [][2]int `json:"runs"` // palette index, run length
+	Width   int      `json:"width"`
+	Height  int      `json:"height"`
+	Depth   int      `json:"depth"`
 }
 
-// json chunk
+// json chunk from save format version 0, only read by migrations now
 type DebugChunkJSON struct {
 	// voxel name encoding map (for data compression)
 	VoxelNamesShort map[string]int `json:"voxel_names_short"`
@@ -37,6 +54,7 @@ type PlayerJSON struct {
 ## LAYOUT
 WORLD.json - world metadata & block table
 PLAYER.json - player data
+WAYPOINTS.json - named places, see waypoints.go
 TERRAIN /
 	CHUNK_X_Y.bin - RLE block data
 	TAGS_X_Y.json - coordinate-based block data for blocks that have data tags (only if chunk has tagged blocks)
@@ -47,60 +65,103 @@ ENTITY /
 // fit metadata to world
 func (world *World) ApplyMetadata(worldJSON WorldMetadata) {
 	world.Seed = worldJSON.Seed
-	world.SavePath = worldJSON.SavePath
+	world.Name = worldJSON.Name
+	world.LastPlayed = worldJSON.LastPlayed
+	// the save path isn't applied, the save is wherever it was loaded from
 }
 
 // Convert a World to a WorldJSON
 func (world *World) WorldToJSON() (worldJSON WorldMetadata) {
+	worldJSON.FormatVersion = SaveFormatVersion
+	worldJSON.Name = world.Name
 	worldJSON.Seed = world.Seed
 	worldJSON.SavePath = world.SavePath
+	worldJSON.LastPlayed = world.LastPlayed
 
 	return
 }
 
 // Convert a ChunkJSON to a Chunk
-func (chunkJSON DebugChunkJSON) JSONToChunk() (chunk Chunk) {
+func (chunkJSON ChunkJSON) JSONToChunk() (chunk Chunk, err error) {
 	chunk.Depth = chunkJSON.Depth
 	chunk.Width = chunkJSON.Width
 	chunk.Height = chunkJSON.Height
 	chunk.Voxels = make([]VoxelPointer, chunkJSON.Width*chunkJSON.Height*chunkJSON.Depth)
-	for i := range chunk.Voxels {
-		chunk.Voxels[i] = defaultVoxelDictionary.GetVoxelPointerTo(invertMap(chunkJSON.VoxelNamesShort)[chunkJSON.VoxelNames[i]])
+
+	// look up every palette entry once
+	palette := make([]VoxelPointer, len(chunkJSON.Palette))
+	for i, name := range chunkJSON.Palette {
+		palette[i] = defaultVoxelDictionary.GetVoxelPointerTo(name)
 	}
 
-	return
+	// expand the runs
+	i := 0
+	for _, run := range chunkJSON.Runs {
+		if run[0] < 0 || run[0] >= len(palette) || run[1] < 0 || i+run[1] > len(chunk.Voxels) {
+			return Chunk{}, fmt.Errorf("chunk has an invalid run %v", run)
+		}
+		for j := 0; j < run[1]; j++ {
+			chunk.Voxels[i] = palette[run[0]]
+			i++
+		}
+	}
+	if i != len(chunk.Voxels) {
+		return Chunk{}, fmt.Errorf("chunk has %d voxels, expected %d", i, len(chunk.Voxels))
+	}
+
+	return chunk, nil
 }
 
-// Convert a Chunk to a ChunkJSON DEBUG OBSOLETE
-func (chunk Chunk) ChunkToJSON() (chunkJSON DebugChunkJSON) {
+// Convert a Chunk to a ChunkJSON
+func (chunk Chunk) ChunkToJSON() (chunkJSON ChunkJSON) {
 	chunkJSON.Depth = chunk.Depth
 	chunkJSON.Width = chunk.Width
 	chunkJSON.Height = chunk.Height
-	chunkJSON.VoxelNames = make([]int, len(chunk.Voxels))
-	chunkJSON.VoxelNamesShort = make(map[string]int)
-
-	// voxel name encoding map (for data compression)
 
-	// get all the unique voxel names
-	allVoxelNames := make([]string, 0)
+	names := make([]string, len(chunk.Voxels))
 	for i := range chunk.Voxels {
-		allVoxelNames = append(allVoxelNames, chunk.Voxels[i].GetVoxel().Name)
+		names[i] = chunk.Voxels[i].GetVoxel().Name
 	}
-	uniqueVoxelNames := uniqueItems(allVoxelNames)
+	chunkJSON.Palette, chunkJSON.Runs = encodeVoxelRuns(names)
 
-	// create the encoding map
-	for i, voxelName := range uniqueVoxelNames {
-		chunkJSON.VoxelNamesShort[voxelName] = i
-	}
+	return
+}
 
-	// encode the voxel names
-	for i := range allVoxelNames {
-		chunkJSON.VoxelNames[i] = chunkJSON.VoxelNamesShort[allVoxelNames[i]]
-	}
+// run-length encode a list of voxel names against a palette
+func encodeVoxelRuns(names []string) (palette []string, runs [][2]int) {
+	paletteIndex := make(map[string]int)
+	for _, name := range names {
+		index, ok := paletteIndex[name]
+		if !ok {
+			index = len(palette)
+			paletteIndex[name] = index
+			palette = append(palette, name)
+		}
 
+		// extend the last run or start a new one
+		if len(runs) > 0 && runs[len(runs)-1][0] == index {
+			runs[len(runs)-1][1]++
+		} else {
+			runs = append(runs, [2]int{index, 1})
+		}
+	}
 	return
 }
 
+// get the voxel names out of a version 0 chunk
+func (chunkJSON DebugChunkJSON) voxelNames() (names []string, err error) {
+	shortNames := invertMap(chunkJSON.VoxelNamesShort)
+	names = make([]string, len(chunkJSON.VoxelNames))
+	for i, short := range chunkJSON.VoxelNames {
+		name, ok := shortNames[short]
+		if !ok {
+			return nil, fmt.Errorf("chunk uses unknown voxel name %d", short)
+		}
+		names[i] = name
+	}
+	return names, nil
+}
+
 // convert player into json
 func (player Player) ToJSON() (playerJSON PlayerJSON) {
 	return PlayerJSON{
@@ -116,6 +177,7 @@ func (playerJSON PlayerJSON) ToPlayer() (player Player) {
 		Velocity: Vec3{X: playerJSON.Velocity[0], Y: playerJSON.Velocity[1], Z: playerJSON.Velocity[2]},
 		Drag:     Vec3{.9, .9, .9},
 		Texture:  "Default",
+		Facing:   "south",
 	}
 }
 
@@ -134,9 +196,8 @@ func (game *Game) MakeEmptySave() (err error) {
 }
 
 // write non-chunk-data
-func (game *Game) WriteData() (err error) {
+func (game *Game) WriteData(playerJSON PlayerJSON) (err error) {
 	worldJSON := game.World.WorldToJSON()
-	playerJSON := game.Player.ToJSON()
 
 	// marshal the things to json
 	worldJSONData, err := json.Marshal(worldJSON)
@@ -145,19 +206,129 @@ func (game *Game) WriteData() (err error) {
 		return
 	}
 	playerJSONData, err := json.Marshal(playerJSON)
-
-	// write the json to a file
-	err = os.WriteFile(filepath.Join(game.World.SavePath, "world.json"), worldJSONData, 0644)
 	if err != nil {
-		log.Printf("ERROR: Failed to write world metadata: %v", err)
+		log.Printf("ERROR: Failed to marshal player: %v", err)
 		return
 	}
-	err = os.WriteFile(filepath.Join(game.World.SavePath, "player.json"), playerJSONData, 0644)
-	if err != nil {
-		log.Printf("ERROR: Failed to write player save: %v", err)
-		return
+
+	// write the json to a file, skipping anything that hasn't changed since the last write
+	if !bytes.Equal(worldJSONData, game.SavedWorldData) {
+		err = writeFileAtomic(filepath.Join(game.World.SavePath, "world.json"), worldJSONData, 0644)
+		if err != nil {
+			log.Printf("ERROR: Failed to write world metadata: %v", err)
+			return
+		}
+		game.SavedWorldData = worldJSONData
+	}
+	if !bytes.Equal(playerJSONData, game.SavedPlayerData) {
+		err = writeFileAtomic(filepath.Join(game.World.SavePath, "player.json"), playerJSONData, 0644)
+		if err != nil {
+			log.Printf("ERROR: Failed to write player save: %v", err)
+			return
+		}
+		game.SavedPlayerData = playerJSONData
+	}
+
+	return
+}
+
+// write a chunk if it has changed, and mark it as saved.
+// the chunk is copied out under the read lock, so the game keeps going while it's written.
+func (world *World) saveChunkIfDirty(key [2]int) (err error) {
+	world.readLockChunks()
+	chunk, exists := world.Chunks[key]
+	if !exists || !chunk.Dirty {
+		world.readUnlockChunks()
+		return nil
+	}
+	chunkJSON, edits := chunk.ChunkToJSON(), chunk.Edits
+	world.readUnlockChunks()
+
+	if err = world.writeChunkJSON(chunkJSON, key[0], key[1]); err != nil {
+		return err
+	}
+
+	world.lockChunks()
+	defer world.unlockChunks()
+	// if it was edited while it was being written it's still dirty, and gets saved next time
+	if chunk, exists = world.Chunks[key]; exists && chunk.Edits == edits {
+		chunk.Dirty = false
+		world.Chunks[key] = chunk
+	}
+	return nil
+}
+
+// unload a chunk, unless it has changed since it was saved
+func (world *World) unloadChunk(key [2]int) (unloaded bool) {
+	world.lockChunks()
+	defer world.unlockChunks()
+	if chunk, exists := world.Chunks[key]; exists && chunk.Dirty {
+		return false
+	}
+	delete(world.Chunks, key)
+	return true
+}
+
+// the positions of every loaded chunk
+func (world *World) loadedChunks() (keys [][2]int) {
+	world.readLockChunks()
+	defer world.readUnlockChunks()
+	for key := range world.Chunks {
+		keys = append(keys, key)
+	}
+	return
+}
+
+// save everything that has changed right now, e.g. when quitting.
+// chunks stay loaded.
+func (game *Game) SaveWorld() (err error) {
+	return game.saveWorld(game.Player.ToJSON())
+}
+
+// save in the background without holding up the game loop, e.g. when pausing.
+// the player is copied first because the game loop keeps changing it, and CloseWorld waits for the save to finish.
+func (game *Game) SaveWorldInBackground() {
+	player := game.Player.ToJSON()
+	game.Saves.Add(1)
+	go func() {
+		defer game.Saves.Done()
+		if err := game.saveWorld(player); err != nil {
+			log.Printf("ERROR: Failed to save world: %v", err)
+		}
+	}()
+}
+
+// SaveWorld, with the player as it was when the save was asked for
+func (game *Game) saveWorld(player PlayerJSON) (err error) {
+	game.SaveMutex.Lock()
+	defer game.SaveMutex.Unlock()
+
+	// the world was closed in the meantime, don't write a save into the working directory
+	if game.World.SavePath == "" {
+		return fmt.Errorf("no world is open")
+	}
+
+	err = game.saveDirtyChunks()
+	if dataErr := game.WriteData(player); dataErr != nil {
+		err = dataErr
+	}
+	if game.Map != nil {
+		if mapErr := game.Map.Save(); mapErr != nil {
+			log.Printf("ERROR: Failed to save map: %v", mapErr)
+			err = mapErr
+		}
 	}
+	return
+}
 
+// write every loaded chunk that has changed, chunks stay loaded
+func (game *Game) saveDirtyChunks() (err error) {
+	for _, key := range game.World.loadedChunks() {
+		if chunkErr := game.World.saveChunkIfDirty(key); chunkErr != nil {
+			log.Printf("ERROR: Failed to save chunk %d, %d: %v", key[0], key[1], chunkErr)
+			err = chunkErr
+		}
+	}
 	return
 }
 
@@ -228,8 +399,11 @@ func chunkCoordinateFromFileName(fileName string) (x, y int, err error) {
 
 // write a chunk to a file
 func (world *World) WriteChunk(chunk Chunk, x, y int) (err error) {
-	chunkJSON := chunk.ChunkToJSON()
+	return world.writeChunkJSON(chunk.ChunkToJSON(), x, y)
+}
 
+// write a chunk that has already been turned into json to a file
+func (world *World) writeChunkJSON(chunkJSON ChunkJSON, x, y int) (err error) {
 	if pathExists(filepath.Join(world.SavePath, "world.json")) {
 		// marshal the chunk to json
 		jsonData, n_err := json.Marshal(chunkJSON)
@@ -240,7 +414,7 @@ func (world *World) WriteChunk(chunk Chunk, x, y int) (err error) {
 
 		// write the json to a file
 		saveName := chunkFileNameFromCoordinate(x, y)
-		err = os.WriteFile(filepath.Join(world.SavePath, "terrain", saveName), jsonData, 0644) // why this code?
+		err = writeFileAtomic(filepath.Join(world.SavePath, "terrain", saveName), jsonData, 0644)
 		if err != nil {
 			log.Printf("ERROR: Failed to write chunk: %v", err)
 			return
@@ -269,26 +443,28 @@ func (world *World) LoadChunk(x, y int) (chunk Chunk, err error) {
 		decoder := json.NewDecoder(file)
 		defer file.Close()
 
-		var chunkJSON DebugChunkJSON
+		var chunkJSON ChunkJSON
 		err = decoder.Decode(&chunkJSON)
 		if err != nil {
 			return Chunk{}, err
 		}
 
 		// convert the ChunkJSON to a Chunk
-		chunk = chunkJSON.JSONToChunk()
-
-		return chunk, nil
+		return chunkJSON.JSONToChunk()
 	} else {
 		return Chunk{}, fmt.Errorf("Chunk does not exist!") // empty chunk
 	}
-	return
 }
 
 // load game. does not load any chunks
 func (game *Game) LoadGame(savePath string) (err error) {
 	// load world metadata
 	if pathExists(filepath.Join(savePath, "world.json")) {
+		// bring old saves up to date first
+		if err = migrateSave(savePath); err != nil {
+			return fmt.Errorf("failed to migrate save: %v", err)
+		}
+
 		// open the world file
 		var file *os.File
 		file, err = os.Open(filepath.Join(savePath, "world.json"))
@@ -307,9 +483,11 @@ func (game *Game) LoadGame(savePath string) (err error) {
 		}
 		game.World.Initialize(worldJSON.Seed)
 		game.World.ApplyMetadata(worldJSON)
+		// the save is wherever we loaded it from, even if it was moved since it was written
+		game.World.SavePath = savePath
 
 		// load the rest of the data
-		game.LoadData()
+		err = game.LoadData()
 	} else {
 		return fmt.Errorf("Game save does not exist!") // empty chunk
 	}
@@ -329,70 +507,163 @@ func (world *World) chunkExists(x, y int) bool {
 
 // save routine
 
-// chunk loading go routine
-func (game *Game) syncWorldWithDisk() {
-	// timer
-	start := time.Now()
-	first := true
+// SyncState, what syncWorldWithDisk needs to know about the game loop.
+// the game loop publishes it every update with setSyncState, so the sync goroutine never reads fields the game loop is changing.
+type SyncState struct {
+	Center [2]int     // the chunk the player is in
+	Radius int        // how many chunks out from Center to keep loaded
+	Player PlayerJSON // the player, to save
+	Target Vec3       // where a teleport is going
+	Arrive chan Vec3  // gets Target once the chunks around it are loaded, nil when not teleporting
+}
+
+// publish the game loop's state to the sync goroutine
+func (game *Game) setSyncState(state SyncState) {
+	game.SyncMutex.Lock()
+	defer game.SyncMutex.Unlock()
+	game.Sync = state
+}
+
+// the game loop's state as it was last published
+func (game *Game) syncState() SyncState {
+	game.SyncMutex.Lock()
+	defer game.SyncMutex.Unlock()
+	return game.Sync
+}
+
+// make syncWorldWithDisk run a pass now instead of waiting for the ticker
+func (game *Game) wakeSync() {
+	select {
+	case game.SyncWake <- struct{}{}:
+	default:
+	}
+}
+
+// chunk loading go routine, runs until stop is closed and then closes done
+func (game *Game) syncWorldWithDisk(stop, wake <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	// let other instances know we're still using the save
+	refreshed := make(chan struct{})
+	go game.SaveLock.keepFresh(stop, refreshed)
+	defer func() { <-refreshed }()
+
+	ticker := time.NewTicker(time.Duration(game.Settings.SyncInterval * float64(time.Second)))
+	defer ticker.Stop()
 
 	for {
-		if time.Since(start).Seconds() >= IOtimeInterval || first {
-			if first {
-				first = false
+		game.syncWorldPass()
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		case <-wake:
+		}
+	}
+}
+
+// save and unload any chunks that are out of range, and attempt to load any chunks that are in range
+func (game *Game) syncWorldPass() {
+	// don't save at the same time as SaveWorld
+	game.SaveMutex.Lock()
+	defer game.SaveMutex.Unlock()
+
+	chunksToUnload := make([][2]int, 0)
+	chunksToLoad := make([][2]int, 0)
+
+	state := game.syncState()
+	center, distance := state.Center, state.Radius
+	inRange := func(key, around [2]int) bool {
+		return absi(key[0]-around[0]) <= distance && absi(key[1]-around[1]) <= distance
+	}
+
+	// while teleporting, where the player is going is loaded first and both places are kept loaded
+	teleporting := state.Arrive != nil
+	target := positionChunk(state.Target, game.World.ChunkSize)
+	if teleporting {
+		for _, key := range teleportChunks(state.Target, game.World.ChunkSize, distance) {
+			if !game.World.chunkLoaded(key) {
+				chunksToLoad = append(chunksToLoad, key)
 			}
-			start = time.Now()
-
-			if true {
-				// save and unload any chunks that are out of range, and attempt to load any chunks that are in range
-				chunksToUnload := make([][2]int, 0)
-				chunksToLoad := make([][2]int, 0)
-
-				// get out of range chunks
-				for key := range game.World.Chunks {
-					if absi(key[0]-game.CurrentChunk[0]) > chunkLoadDistance || absi(key[1]-game.CurrentChunk[1]) > chunkLoadDistance {
-						chunksToUnload = append(chunksToUnload, key)
-					}
-				}
-
-				// get in range chunks (that aren't already loaded)
-				for x := game.CurrentChunk[0] - chunkLoadDistance; x <= game.CurrentChunk[0]+chunkLoadDistance; x++ {
-					for y := game.CurrentChunk[1] - chunkLoadDistance; y <= game.CurrentChunk[1]+chunkLoadDistance; y++ {
-						if _, exists := game.World.Chunks[[2]int{x, y}]; !exists {
-							chunksToLoad = append(chunksToLoad, [2]int{x, y})
-						}
-					}
-				}
-
-				if len(chunksToUnload) > 0 || len(chunksToLoad) > 0 {
-					// unload and save
-					for _, key := range chunksToUnload {
-						// save
-						chunk := game.World.Chunks[key]
-						err := game.World.WriteChunk(chunk, key[0], key[1])
-						if err != nil {
-							panic(err)
-						}
-						// unload
-						delete(game.World.Chunks, key)
-					}
-
-					// load and generate
-					for _, key := range chunksToLoad {
-						if game.World.chunkExists(key[0], key[1]) {
-							chunk, err := game.World.LoadChunk(key[0], key[1])
-							if err != nil {
-								panic("Unable to load chunk.")
-							}
-							game.World.Chunks[key] = chunk
-						} else {
-							game.World.generateChunk(key, game.World.ChunkSize, game.World.ChunkSize, game.World.ChunkDepth, defaultVoxelDictionary)
-						}
-					}
-				}
-
-				// save all the random data
-				game.WriteData()
+		}
+	}
+
+	// get out of range chunks
+	for _, key := range game.World.loadedChunks() {
+		if !inRange(key, center) && !(teleporting && inRange(key, target)) {
+			chunksToUnload = append(chunksToUnload, key)
+		}
+	}
+
+	// get in range chunks (that aren't already loaded)
+	for x := center[0] - distance; x <= center[0]+distance; x++ {
+		for y := center[1] - distance; y <= center[1]+distance; y++ {
+			if !game.World.chunkLoaded([2]int{x, y}) && !(teleporting && inRange([2]int{x, y}, target)) {
+				chunksToLoad = append(chunksToLoad, [2]int{x, y})
 			}
 		}
 	}
+
+	if len(chunksToUnload) > 0 || len(chunksToLoad) > 0 {
+		// unload and save
+		for _, key := range chunksToUnload {
+			// save, unchanged chunks can just be generated again from the seed
+			err := game.World.saveChunkIfDirty(key)
+			if err != nil {
+				// keep it loaded and try again next time
+				log.Printf("ERROR: Failed to save chunk %d, %d: %v", key[0], key[1], err)
+				continue
+			}
+			// unload, unless it was edited while it was being saved
+			game.World.unloadChunk(key)
+		}
+
+		// load and generate
+		for _, key := range chunksToLoad {
+			game.loadChunk(key)
+		}
+
+		// keep the map on disk as it's explored, not just when the world is saved
+		if err := game.Map.Save(); err != nil {
+			log.Printf("ERROR: Failed to save map: %v", err)
+		}
+	}
+
+	// the player can arrive now. if they already have and the game loop hasn't seen it yet, there's nothing to do
+	if teleporting {
+		select {
+		case state.Arrive <- state.Target:
+		default:
+		}
+	}
+
+	// save all the random data
+	game.WriteData(state.Player)
+
+	game.backupIfDue()
+}
+
+// load a chunk from the save, or generate it if it has never been saved, and add it to the map.
+// the caller holds SaveMutex.
+func (game *Game) loadChunk(key [2]int) {
+	if game.World.chunkExists(key[0], key[1]) {
+		chunk, err := game.World.LoadChunk(key[0], key[1])
+		if err == nil {
+			game.World.storeChunk(key, chunk)
+			game.Map.Explore(&game.World, key)
+			return
+		}
+
+		// move the broken chunk out of the way and generate it again
+		log.Printf("ERROR: Failed to load chunk %d, %d: %v", key[0], key[1], err)
+		quarantinePath, qErr := game.World.quarantineChunk(key[0], key[1])
+		if qErr != nil {
+			log.Printf("ERROR: Failed to quarantine chunk %d, %d: %v", key[0], key[1], qErr)
+		} else {
+			log.Printf("Moved corrupt chunk to %s, regenerating it", quarantinePath)
+		}
+	}
+	game.World.generateChunk(key, game.World.ChunkSize, game.World.ChunkSize, game.World.ChunkDepth, defaultVoxelDictionary)
+	game.Map.Explore(&game.World, key)
 }
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// SAVE SAFETY
+// writes go to a temporary file that is synced and renamed over the real one,
+// so a crash halfway through a write leaves the old file instead of half a file.
+
+// how long a lock file can go without being refreshed before another instance may take it over
+var saveLockStaleAfter = 30 * time.Second
+
+// how often an open save's lock is refreshed, well inside saveLockStaleAfter
+var saveLockRefreshInterval = saveLockStaleAfter / 3
+
+// write a file so that it is either fully written or not changed at all
+func writeFileAtomic(path string, data []byte, perm os.FileMode) (err error) {
+	dir := filepath.Dir(path)
+	temp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	// clean up the temporary file if anything goes wrong
+	defer func() {
+		if err != nil {
+			temp.Close()
+			os.Remove(temp.Name())
+		}
+	}()
+
+	if _, err = temp.Write(data); err != nil {
+		return err
+	}
+	if err = temp.Sync(); err != nil {
+		return err
+	}
+	if err = temp.Close(); err != nil {
+		return err
+	}
+	if err = os.Chmod(temp.Name(), perm); err != nil {
+		return err
+	}
+	if err = os.Rename(temp.Name(), path); err != nil {
+		return err
+	}
+
+	// sync the directory so the rename itself survives a crash.
+	// not every platform lets you do this, so errors are ignored.
+	if dirFile, dirErr := os.Open(dir); dirErr == nil {
+		dirFile.Sync()
+		dirFile.Close()
+	}
+	return nil
+}
+
+// SaveLock, stops two instances of the game from writing to the same save.
+type SaveLock struct {
+	Path string
+}
+
+// take the lock on a save directory
+func acquireSaveLock(savePath string) (lock *SaveLock, err error) {
+	path := filepath.Join(savePath, "session.lock")
+
+	for attempt := 0; attempt < 2; attempt++ {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			hostname, _ := os.Hostname()
+			fmt.Fprintf(file, "%d@%s\n", os.Getpid(), hostname)
+			file.Close()
+			return &SaveLock{Path: path}, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+
+		// someone has the lock, but if they stopped refreshing it they probably crashed
+		info, statErr := os.Stat(path)
+		if statErr != nil || time.Since(info.ModTime()) < saveLockStaleAfter || takeOverStaleLock(path) != nil {
+			owner, _ := os.ReadFile(path)
+			return nil, fmt.Errorf("save %s is already open in another instance (%s)", savePath, string(owner))
+		}
+	}
+	return nil, fmt.Errorf("failed to take the lock on %s", savePath)
+}
+
+// move a stale lock out of the way, so the lock can be created again.
+// two instances can both see the same stale lock, so it's renamed rather than removed:
+// only one of them can move it, and if the file that got moved isn't stale any more,
+// another instance already took over and its lock is put back.
+func takeOverStaleLock(path string) error {
+	log.Printf("Taking over stale save lock %s", path)
+	moved := fmt.Sprintf("%s.stale-%d-%d", path, os.Getpid(), time.Now().UnixNano())
+	if err := os.Rename(path, moved); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil // someone else moved it first, so whoever creates the lock next gets it
+		}
+		return err
+	}
+	if info, err := os.Stat(moved); err != nil || time.Since(info.ModTime()) < saveLockStaleAfter {
+		os.Rename(moved, path)
+		return errors.New("another instance took over the lock first")
+	}
+	os.Remove(moved)
+	return nil
+}
+
+// show that the lock is still in use
+func (lock *SaveLock) Refresh() error {
+	if lock == nil {
+		return nil
+	}
+	now := time.Now()
+	return os.Chtimes(lock.Path, now, now)
+}
+
+// refresh the lock until stop is closed, then close done.
+// this runs on its own so a slow sync pass or a long sync interval can't let the lock go stale.
+func (lock *SaveLock) keepFresh(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(saveLockRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		if err := lock.Refresh(); err != nil {
+			log.Printf("ERROR: Failed to refresh save lock: %v", err)
+		}
+	}
+}
+
+// give the lock back
+func (lock *SaveLock) Release() error {
+	if lock == nil {
+		return nil
+	}
+	return os.Remove(lock.Path)
+}
+
+// move a chunk file that can't be loaded out of the way, so it can be regenerated.
+// the file is kept in terrain/corrupt in case someone wants to look at it.
+func (world *World) quarantineChunk(x, y int) (quarantinePath string, err error) {
+	fileName := chunkFileNameFromCoordinate(x, y)
+	corruptPath := filepath.Join(world.SavePath, "terrain", "corrupt")
+	if err = os.MkdirAll(corruptPath, 0755); err != nil {
+		return "", err
+	}
+	quarantinePath = filepath.Join(corruptPath, fmt.Sprintf("%s.%s", fileName, time.Now().Format("20060102-150405")))
+	err = os.Rename(filepath.Join(world.SavePath, "terrain", fileName), quarantinePath)
+	return
+}
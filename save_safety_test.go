@@ -0,0 +1,161 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSaveLockHeldByAnotherInstance(t *testing.T) {
+	savePath := t.TempDir()
+	lock, err := acquireSaveLock(savePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a fresh lock belongs to someone who is still playing
+	_, err = acquireSaveLock(savePath)
+	if err == nil {
+		t.Fatal("took a lock another instance is holding")
+	}
+	if !strings.Contains(err.Error(), "already open") {
+		t.Errorf("error = %v, expected it to say the save is already open", err)
+	}
+
+	// and can be taken once they let go
+	lock.Release()
+	lock, err = acquireSaveLock(savePath)
+	if err != nil {
+		t.Fatalf("failed to take a released lock: %v", err)
+	}
+	lock.Release()
+}
+
+func TestSaveLockStaleTakeover(t *testing.T) {
+	savePath := t.TempDir()
+	path := filepath.Join(savePath, "session.lock")
+	if err := os.WriteFile(path, []byte("1234@crashed\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * saveLockStaleAfter)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	lock, err := acquireSaveLock(savePath)
+	if err != nil {
+		t.Fatalf("failed to take over a stale lock: %v", err)
+	}
+	defer lock.Release()
+	if owner, _ := os.ReadFile(path); strings.Contains(string(owner), "crashed") {
+		t.Errorf("lock still belongs to the crashed instance: %s", owner)
+	}
+}
+
+func TestSaveLockStaleTakeoverRace(t *testing.T) {
+	savePath := t.TempDir()
+	path := filepath.Join(savePath, "session.lock")
+	if err := os.WriteFile(path, []byte("1234@crashed\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * saveLockStaleAfter)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	// two instances see the same stale lock, and the first one takes over before the second does
+	lock, err := acquireSaveLock(savePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lock.Release()
+	owner, _ := os.ReadFile(path)
+
+	if err := takeOverStaleLock(path); err == nil {
+		t.Error("took over a lock another instance had just taken")
+	}
+	if now, _ := os.ReadFile(path); string(now) != string(owner) {
+		t.Errorf("lock = %q after the second takeover, want the first instance's %q", now, owner)
+	}
+	if _, err := acquireSaveLock(savePath); err == nil {
+		t.Error("both instances have the lock")
+	}
+	if leftovers, _ := filepath.Glob(path + ".stale-*"); len(leftovers) != 0 {
+		t.Errorf("left moved locks behind: %v", leftovers)
+	}
+}
+
+func TestCorruptChunkIsQuarantinedAndRegenerated(t *testing.T) {
+	savePath := copyFixtureSave(t, "v1")
+	game := &Game{}
+	if err := game.LoadGame(savePath); err != nil {
+		t.Fatal(err)
+	}
+	game.Map = newWorldMap("", game.World.ChunkSize, nil)
+
+	chunkPath := filepath.Join(savePath, "terrain", chunkFileNameFromCoordinate(0, 0))
+	if err := os.WriteFile(chunkPath, []byte("{\"palette\": [\"Sto"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	game.loadChunk([2]int{0, 0})
+
+	// the broken file is kept to one side, as it was
+	if pathExists(chunkPath) {
+		t.Errorf("corrupt chunk is still in terrain/")
+	}
+	quarantined, err := filepath.Glob(filepath.Join(savePath, "terrain", "corrupt", chunkFileNameFromCoordinate(0, 0)+".*"))
+	if err != nil || len(quarantined) != 1 {
+		t.Fatalf("expected 1 quarantined chunk, found %v", quarantined)
+	}
+	if data, _ := os.ReadFile(quarantined[0]); !strings.HasPrefix(string(data), "{\"palette\"") {
+		t.Errorf("quarantined chunk = %s, expected the corrupt file", data)
+	}
+
+	// and the chunk is generated again from the seed
+	chunk, ok := game.World.Chunks[[2]int{0, 0}]
+	if !ok {
+		t.Fatal("chunk wasn't loaded")
+	}
+	fresh := World{}
+	fresh.Initialize(game.World.Seed)
+	fresh.generateChunk([2]int{0, 0}, fresh.ChunkSize, fresh.ChunkSize, fresh.ChunkDepth, defaultVoxelDictionary)
+	expected := fresh.Chunks[[2]int{0, 0}]
+	for i := range chunk.Voxels {
+		if chunk.Voxels[i] != expected.Voxels[i] {
+			t.Fatalf("voxel %d = %s, expected %s from the seed", i, chunk.Voxels[i].GetVoxel().Name, expected.Voxels[i].GetVoxel().Name)
+		}
+	}
+}
+
+func TestSaveLockKeepsFresh(t *testing.T) {
+	defer func(interval time.Duration) { saveLockRefreshInterval = interval }(saveLockRefreshInterval)
+	saveLockRefreshInterval = 10 * time.Millisecond
+
+	lock, err := acquireSaveLock(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lock.Release()
+
+	// pretend nobody has touched the lock for a while
+	old := time.Now().Add(-saveLockStaleAfter / 2)
+	if err := os.Chtimes(lock.Path, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	stop, done := make(chan struct{}), make(chan struct{})
+	go lock.keepFresh(stop, done)
+	time.Sleep(100 * time.Millisecond)
+	close(stop)
+	<-done
+
+	info, err := os.Stat(lock.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.ModTime().After(old.Add(time.Second)) {
+		t.Errorf("lock wasn't refreshed, last modified %v", info.ModTime())
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// the voxels in the fixture chunk, in storage order
+var fixtureChunkVoxels = []string{"Stone", "Stone", "Stone", "Stone", "Grass", "Air", "Grass", "Air"}
+
+// copy a fixture save into a temporary directory so tests can modify it
+func copyFixtureSave(t *testing.T, version string) string {
+	t.Helper()
+	savePath := filepath.Join(t.TempDir(), "world")
+	if err := os.CopyFS(savePath, os.DirFS(filepath.Join("testdata", "saves", version))); err != nil {
+		t.Fatalf("failed to copy fixture save: %v", err)
+	}
+	return savePath
+}
+
+// check that a loaded save matches the fixture
+func checkFixtureSave(t *testing.T, game *Game, savePath string) {
+	t.Helper()
+	if game.World.Seed != 42 {
+		t.Errorf("seed = %d, expected 42", game.World.Seed)
+	}
+	if game.Player.Position != (Vec3{3, 4, 20}) {
+		t.Errorf("player position = %v, expected {3 4 20}", game.Player.Position)
+	}
+
+	version, err := readSaveFormatVersion(savePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != SaveFormatVersion {
+		t.Errorf("save format version = %d, expected %d", version, SaveFormatVersion)
+	}
+
+	game.World.SavePath = savePath
+	chunk, err := game.World.LoadChunk(0, 0)
+	if err != nil {
+		t.Fatalf("failed to load chunk: %v", err)
+	}
+	names := make([]string, len(chunk.Voxels))
+	for i := range chunk.Voxels {
+		names[i] = chunk.Voxels[i].GetVoxel().Name
+	}
+	if !slices.Equal(names, fixtureChunkVoxels) {
+		t.Errorf("chunk voxels = %v, expected %v", names, fixtureChunkVoxels)
+	}
+}
+
+func TestLoadSaveV0(t *testing.T) {
+	savePath := copyFixtureSave(t, "v0")
+
+	game := &Game{}
+	if err := game.LoadGame(savePath); err != nil {
+		t.Fatalf("failed to load save: %v", err)
+	}
+	checkFixtureSave(t, game, savePath)
+
+	// the old save should have been backed up before it was touched
+	backups, err := listBackups(savePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 1 || backups[0].Reason != "before-migration-v0" {
+		t.Fatalf("expected 1 before-migration-v0 backup, found %+v", backups)
+	}
+	archive, err := zip.OpenReader(backups[0].Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer archive.Close()
+	if _, err := archive.Open("terrain/chunk0_0.json"); err != nil {
+		t.Errorf("backup is missing the chunk: %v", err)
+	}
+}
+
+func TestLoadHalfMigratedSaveV0(t *testing.T) {
+	// chunk0_0 was migrated before the game stopped, chunk1_0 wasn't
+	savePath := copyFixtureSave(t, "v0-partial")
+
+	game := &Game{}
+	if err := game.LoadGame(savePath); err != nil {
+		t.Fatalf("failed to load save: %v", err)
+	}
+	checkFixtureSave(t, game, savePath)
+
+	chunk, err := game.World.LoadChunk(1, 0)
+	if err != nil {
+		t.Fatalf("failed to load chunk: %v", err)
+	}
+	for i := range chunk.Voxels {
+		if name := chunk.Voxels[i].GetVoxel().Name; name != fixtureChunkVoxels[i] {
+			t.Errorf("voxel %d = %s, expected %s", i, name, fixtureChunkVoxels[i])
+		}
+	}
+}
+
+func TestMigrateRejectsWrongVoxelCount(t *testing.T) {
+	savePath := copyFixtureSave(t, "v0")
+	path := filepath.Join(savePath, "terrain", "chunk0_0.json")
+	broken := `{"voxel_names_short":{"Stone":0},"voxel_names":[0,0,0],"width":2,"height":2,"depth":2}`
+	if err := os.WriteFile(path, []byte(broken), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := migrateSave(savePath); err == nil {
+		t.Fatal("expected migrating a chunk with the wrong number of voxels to fail")
+	}
+	// the chunk is left as it was
+	if data, _ := os.ReadFile(path); string(data) != broken {
+		t.Errorf("chunk was rewritten: %s", data)
+	}
+}
+
+func TestLoadSaveV1(t *testing.T) {
+	savePath := copyFixtureSave(t, "v1")
+
+	game := &Game{}
+	if err := game.LoadGame(savePath); err != nil {
+		t.Fatalf("failed to load save: %v", err)
+	}
+	checkFixtureSave(t, game, savePath)
+
+	// up to date saves are left alone
+	if backups, _ := filepath.Glob(savePath + ".backup-*"); len(backups) != 0 {
+		t.Errorf("expected no backups, found %v", backups)
+	}
+}
+
+func TestLoadSaveFromNewerVersion(t *testing.T) {
+	savePath := copyFixtureSave(t, "v1")
+	if err := writeSaveFormatVersion(savePath, SaveFormatVersion+1); err != nil {
+		t.Fatal(err)
+	}
+
+	game := &Game{}
+	if err := game.LoadGame(savePath); err == nil {
+		t.Fatal("expected loading a save from a newer version to fail")
+	}
+}
+
+func TestChunkJSONRoundTrip(t *testing.T) {
+	chunk := Chunk{Width: 2, Height: 2, Depth: 2, Voxels: make([]VoxelPointer, 8)}
+	for i, name := range fixtureChunkVoxels {
+		chunk.Voxels[i] = defaultVoxelDictionary.GetVoxelPointerTo(name)
+	}
+
+	chunkJSON := chunk.ChunkToJSON()
+	if len(chunkJSON.Runs) != 5 {
+		t.Errorf("expected 5 runs, got %v", chunkJSON.Runs)
+	}
+
+	decoded, err := chunkJSON.JSONToChunk()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range chunk.Voxels {
+		if decoded.Voxels[i].GetVoxel().Name != fixtureChunkVoxels[i] {
+			t.Errorf("voxel %d = %s, expected %s", i, decoded.Voxels[i].GetVoxel().Name, fixtureChunkVoxels[i])
+		}
+	}
+}
+
+func TestSaveWorldOnlyWritesDirtyChunks(t *testing.T) {
+	savePath := copyFixtureSave(t, "v1")
+
+	game := &Game{}
+	if err := game.LoadGame(savePath); err != nil {
+		t.Fatalf("failed to load save: %v", err)
+	}
+	chunk, err := game.World.LoadChunk(0, 0)
+	if err != nil {
+		t.Fatalf("failed to load chunk: %v", err)
+	}
+	game.World.Chunks[[2]int{0, 0}] = chunk
+	game.World.generateChunk([2]int{-1, 0}, game.World.ChunkSize, game.World.ChunkSize, game.World.ChunkDepth, defaultVoxelDictionary)
+
+	// nothing changed, so nothing should be written
+	if err := game.SaveWorld(); err != nil {
+		t.Fatal(err)
+	}
+	if game.World.chunkExists(-1, 0) {
+		t.Errorf("clean generated chunk was written")
+	}
+
+	// negative coordinates land in the chunk to the left
+	if !game.World.SetVoxel(-1, 0, 0, defaultVoxelDictionary.GetVoxelPointerTo("Cobblestone")) {
+		t.Fatalf("failed to set voxel")
+	}
+	if !game.World.Chunks[[2]int{-1, 0}].Dirty {
+		t.Errorf("edited chunk is not dirty")
+	}
+	if voxel, _ := game.World.GetVoxel(-1, 0, 0); voxel.Name != "Cobblestone" {
+		t.Errorf("voxel at -1, 0, 0 = %s, expected Cobblestone", voxel.Name)
+	}
+
+	if err := game.SaveWorld(); err != nil {
+		t.Fatal(err)
+	}
+	if !game.World.chunkExists(-1, 0) {
+		t.Errorf("dirty chunk was not written")
+	}
+	if game.World.Chunks[[2]int{-1, 0}].Dirty {
+		t.Errorf("chunk is still dirty after saving")
+	}
+	saved, err := game.World.LoadChunk(-1, 0)
+	if err != nil {
+		t.Fatalf("failed to load saved chunk: %v", err)
+	}
+	if name := saved.GetVoxel(game.World.ChunkSize-1, 0, 0).Name; name != "Cobblestone" {
+		t.Errorf("saved voxel = %s, expected Cobblestone", name)
+	}
+}
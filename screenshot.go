@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// SCREENSHOTS
+// screenshots are the world as it's drawn on screen without anything drawn over it,
+// posters are every loaded chunk drawn at full size with renderIsometric.
+// both go in the screenshots directory, named by when they were taken and the world's seed.
+
+// where screenshots are kept
+var screenshotsPath = "screenshots"
+
+// the file name for a screenshot, kind is "" for a normal one or e.g. "poster"
+func screenshotFileName(when time.Time, seed int64, kind string) string {
+	name := fmt.Sprintf("%s-seed%d", when.Format(backupTimeLayout), seed)
+	if kind != "" {
+		name += "-" + kind
+	}
+	return name + ".png"
+}
+
+// scale an image up by a whole number, without any filtering, so every pixel stays square
+func upscaleImage(img image.Image, scale int) image.Image {
+	if scale <= 1 {
+		return img
+	}
+	bounds := img.Bounds()
+	source, ok := img.(*image.RGBA)
+	if !ok {
+		source = image.NewRGBA(bounds)
+		draw.Draw(source, bounds, img, bounds.Min, draw.Src)
+	}
+
+	width := bounds.Dx() * scale
+	scaled := image.NewRGBA(image.Rect(0, 0, width, bounds.Dy()*scale))
+	for y := 0; y < bounds.Dy(); y++ {
+		// stretch the row out, then copy it down for the rest of the block
+		row := scaled.Pix[y*scale*scaled.Stride : y*scale*scaled.Stride+width*4]
+		for x := 0; x < bounds.Dx(); x++ {
+			offset := source.PixOffset(bounds.Min.X+x, bounds.Min.Y+y)
+			pixel := source.Pix[offset : offset+4]
+			for i := 0; i < scale; i++ {
+				copy(row[(x*scale+i)*4:], pixel)
+			}
+		}
+		for i := 1; i < scale; i++ {
+			copy(scaled.Pix[(y*scale+i)*scaled.Stride:], row)
+		}
+	}
+	return scaled
+}
+
+// write a screenshot to the screenshots directory, never over one that's already there
+func writeScreenshot(directory string, img image.Image, when time.Time, seed int64, kind string) (path string, err error) {
+	if err = os.MkdirAll(directory, 0755); err != nil {
+		return "", err
+	}
+	name := screenshotFileName(when, seed, kind)
+	path = filepath.Join(directory, name)
+	for n := 2; pathExists(path); n++ {
+		path = filepath.Join(directory, fmt.Sprintf("%s-%d.png", strings.TrimSuffix(name, ".png"), n))
+	}
+	return path, writePNG(path, img)
+}
+
+// the smallest area of chunks that has every loaded chunk in it
+func (world *World) loadedChunkBounds() (minChunk, maxChunk [2]int, ok bool) {
+	for key := range world.Chunks {
+		if !ok {
+			minChunk, maxChunk, ok = key, key, true
+			continue
+		}
+		minChunk = [2]int{min(minChunk[0], key[0]), min(minChunk[1], key[1])}
+		maxChunk = [2]int{max(maxChunk[0], key[0]), max(maxChunk[1], key[1])}
+	}
+	return
+}
+
+// draw every loaded chunk at full size, whatever is on screen
+func (world *World) renderPoster(direction [4]int, textures offlineTextures) (*image.RGBA, error) {
+	minChunk, maxChunk, ok := world.loadedChunkBounds()
+	if !ok {
+		return nil, fmt.Errorf("no chunks are loaded")
+	}
+	return world.renderIsometric(minChunk, maxChunk, direction, textures), nil
+}
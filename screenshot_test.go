@@ -0,0 +1,115 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScreenshotFileName(t *testing.T) {
+	when := time.Date(2024, 3, 9, 14, 5, 7, 250*int(time.Millisecond), time.Local)
+	if name := screenshotFileName(when, 42, ""); name != "20240309-140507.250-seed42.png" {
+		t.Errorf("screenshot is called %s", name)
+	}
+	if name := screenshotFileName(when, -7, "poster"); name != "20240309-140507.250-seed-7-poster.png" {
+		t.Errorf("poster is called %s", name)
+	}
+}
+
+func TestUpscaleImage(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	img.SetRGBA(0, 0, color.RGBA{255, 0, 0, 255})
+	img.SetRGBA(1, 0, color.RGBA{0, 0, 255, 255})
+
+	if upscaleImage(img, 1) != image.Image(img) {
+		t.Errorf("scaling by 1 made a new image")
+	}
+	scaled := upscaleImage(img, 3)
+	if scaled.Bounds() != image.Rect(0, 0, 6, 3) {
+		t.Fatalf("scaled image is %v", scaled.Bounds())
+	}
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 6; x++ {
+			if got, want := scaled.At(x, y), img.At(x/3, 0); got != want {
+				t.Errorf("pixel %d, %d is %v, expected %v", x, y, got, want)
+			}
+		}
+	}
+
+	// posters are cropped sub images, which don't start at 0, 0
+	scaled = upscaleImage(img.SubImage(image.Rect(1, 0, 2, 1)), 2)
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 2; x++ {
+			if got, want := scaled.At(x, y), img.At(1, 0); got != want {
+				t.Errorf("cropped pixel %d, %d is %v, expected %v", x, y, got, want)
+			}
+		}
+	}
+}
+
+func TestWorldSnapshot(t *testing.T) {
+	world := World{}
+	world.Initialize(42)
+	world.generateChunk([2]int{0, 0}, world.ChunkSize, world.ChunkSize, world.ChunkDepth, defaultVoxelDictionary)
+
+	snapshot := world.snapshot()
+	world.SetVoxel(0, 0, 0, defaultVoxelDictionary.GetVoxelPointerTo("Cobblestone"))
+	chunk := snapshot.Chunks[[2]int{0, 0}]
+	if name := chunk.GetVoxel(0, 0, 0).Name; name == "Cobblestone" {
+		t.Errorf("snapshot voxel changed to %s after editing the world", name)
+	}
+}
+
+func TestWriteScreenshot(t *testing.T) {
+	directory := filepath.Join(t.TempDir(), "screenshots")
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	when := time.Now()
+
+	// two at the same moment don't write over each other
+	first, err := writeScreenshot(directory, img, when, 1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := writeScreenshot(directory, img, when, 1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == second || !pathExists(first) || !pathExists(second) {
+		t.Errorf("wrote %s and %s", first, second)
+	}
+	if !strings.HasSuffix(second, "-2.png") {
+		t.Errorf("second screenshot is %s", second)
+	}
+}
+
+func TestRenderPoster(t *testing.T) {
+	world := World{ChunkSize: 32, ChunkDepth: 64, Chunks: make(map[[2]int]Chunk)}
+	if _, err := world.renderPoster(SOUTH, nil); err == nil {
+		t.Errorf("drew a poster of nothing")
+	}
+
+	flat := flatTestWorld(10)
+	world.Chunks[[2]int{0, 0}] = flat.Chunks[[2]int{0, 0}]
+	world.Chunks[[2]int{-2, 1}] = flat.Chunks[[2]int{0, 0}]
+	minChunk, maxChunk, ok := world.loadedChunkBounds()
+	if !ok || minChunk != [2]int{-2, 0} || maxChunk != [2]int{0, 1} {
+		t.Errorf("loaded chunks are %v to %v (%v)", minChunk, maxChunk, ok)
+	}
+
+	textures, err := loadOfflineTextures(&defaultVoxelDictionary, "assets/block_atlas.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	poster, err := world.renderPoster(SOUTH, textures)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// two chunks far apart are wider than one
+	one := world.renderIsometric([2]int{0, 0}, [2]int{0, 0}, SOUTH, textures)
+	if poster.Bounds().Dx() <= one.Bounds().Dx() {
+		t.Errorf("poster is %v, one chunk is %v", poster.Bounds(), one.Bounds())
+	}
+}
@@ -1,6 +1,140 @@
 package main
 
-// game settings
-// var chunkUpdateDistance = 2
-var chunkLoadDistance = 4
-var IOtimeInterval float64 = 2 //s
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// SETTINGS
+// settings are per user, not per world, so they live in the user's config directory.
+// anything missing from the file gets its default, and anything out of range is put back in range.
+
+// Settings, everything the player can change about how the game runs.
+type Settings struct {
+	RenderDistance  int     `json:"render_distance"` // chunks loaded around the player in each direction
+	SyncInterval    float64 `json:"sync_interval"`   // seconds between loading and saving chunks
+	VSync           bool    `json:"vsync"`
+	TPS             int     `json:"tps"`              // updates per second
+	WindowScale     int     `json:"window_scale"`     // the window is this many times the size of the screen
+	DepthShift      bool    `json:"depth_shift"`      // wobble the world, toggled in game with \
+	Debug           bool    `json:"debug"`            // start with the debug overlay on
+	CutawayFade     bool    `json:"cutaway_fade"`     // the cut-away view fades what's above the player, instead of hiding it
+	Zoom            float64 `json:"zoom"`             // how far in the camera is, one of zoomLevels
+	Minimap         bool    `json:"minimap"`          // show the minimap in the corner
+	ScreenshotScale int     `json:"screenshot_scale"` // screenshots are this many times the size of the screen
+
+	Bindings map[Action]Binding `json:"bindings"` // controls, see actions.go
+}
+
+// the logical screen size, the window is scaled up from this
+const screenWidth, screenHeight = 640, 360
+
+// limits for the settings that have them
+const (
+	minRenderDistance, maxRenderDistance           = 1, 12
+	minSyncInterval, maxSyncInterval       float64 = .25, 60
+	minTPS, maxTPS                                 = 20, 240
+	minWindowScale, maxWindowScale                 = 1, 4
+	minScreenshotScale, maxScreenshotScale         = 1, 8
+)
+
+// the settings used when there is no settings file
+func defaultSettings() Settings {
+	return Settings{
+		RenderDistance:  4,
+		SyncInterval:    2,
+		VSync:           true,
+		TPS:             60,
+		WindowScale:     2,
+		DepthShift:      false,
+		Debug:           false,
+		CutawayFade:     false,
+		Zoom:            1,
+		Minimap:         true,
+		ScreenshotScale: 1,
+		Bindings:        defaultBindings(),
+	}
+}
+
+// put every setting back in range, returning what had to be fixed
+func (settings *Settings) Validate() (problems []string) {
+	clampSetting := func(name string, value *int, low, high int) {
+		if *value < low || *value > high {
+			problems = append(problems, fmt.Sprintf("%s %d is out of range %d-%d", name, *value, low, high))
+			*value = clampi(*value, low, high)
+		}
+	}
+	clampSetting("render_distance", &settings.RenderDistance, minRenderDistance, maxRenderDistance)
+	clampSetting("tps", &settings.TPS, minTPS, maxTPS)
+	clampSetting("window_scale", &settings.WindowScale, minWindowScale, maxWindowScale)
+	clampSetting("screenshot_scale", &settings.ScreenshotScale, minScreenshotScale, maxScreenshotScale)
+
+	if settings.SyncInterval < minSyncInterval || settings.SyncInterval > maxSyncInterval {
+		problems = append(problems, fmt.Sprintf("sync_interval %g is out of range %g-%g", settings.SyncInterval, minSyncInterval, maxSyncInterval))
+		settings.SyncInterval = max(minSyncInterval, min(settings.SyncInterval, maxSyncInterval))
+	}
+
+	if nearest := nearestZoom(settings.Zoom); nearest != settings.Zoom {
+		problems = append(problems, fmt.Sprintf("zoom %g isn't a zoom level, using %g", settings.Zoom, nearest))
+		settings.Zoom = nearest
+	}
+
+	// every action needs a binding, even if it's an empty one, and there's no point keeping ones for actions that don't exist
+	if settings.Bindings == nil {
+		settings.Bindings = defaultBindings()
+	}
+	for action := range settings.Bindings {
+		if _, ok := actionInfo(action); !ok {
+			problems = append(problems, fmt.Sprintf("there is no action called %s", action))
+			delete(settings.Bindings, action)
+		}
+	}
+	for _, info := range actions {
+		if _, ok := settings.Bindings[info.Action]; !ok {
+			settings.Bindings[info.Action] = info.Default
+		}
+	}
+	return
+}
+
+// where the settings file goes, e.g. ~/.config/isometrica/settings.json
+func settingsPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "isometrica", "settings.json"), nil
+}
+
+// read the settings file, falling back to the defaults for anything that's missing or broken
+func loadSettings(path string) (settings Settings, problems []string, err error) {
+	settings = defaultSettings()
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return settings, nil, nil
+	} else if err != nil {
+		return settings, nil, err
+	}
+
+	// decode over the defaults, so fields the file doesn't have keep them.
+	// bindings are a map, so actions the file doesn't mention keep theirs too.
+	if err = json.Unmarshal(data, &settings); err != nil {
+		return defaultSettings(), nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return settings, settings.Validate(), nil
+}
+
+// write the settings file
+func saveSettings(path string, settings Settings) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(settings, "", "\t")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data, 0644)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadSettings(t *testing.T) {
+	directory := t.TempDir()
+
+	// no file is just the defaults
+	settings, problems, err := loadSettings(filepath.Join(directory, "missing.json"))
+	if err != nil || len(problems) > 0 || !reflect.DeepEqual(settings, defaultSettings()) {
+		t.Errorf("missing file = %+v, %v, %v, expected the defaults", settings, problems, err)
+	}
+
+	// missing fields keep their defaults and out of range ones are fixed
+	path := filepath.Join(directory, "settings.json")
+	data := `{"render_distance": 100, "vsync": false, "window_scale": 0, "zoom": 1.7, "bindings": {"jump": {"keys": ["J"]}, "teleport": {"keys": ["T"]}}}`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	settings, problems, err = loadSettings(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := defaultSettings()
+	expected.RenderDistance = maxRenderDistance
+	expected.VSync = false
+	expected.WindowScale = minWindowScale
+	expected.Zoom = 2
+	expected.Bindings[ACTION_JUMP] = Binding{Keys: []string{"J"}}
+	if !reflect.DeepEqual(settings, expected) {
+		t.Errorf("settings = %+v, expected %+v", settings, expected)
+	}
+	if len(problems) != 4 {
+		t.Errorf("problems = %v, expected 4", problems)
+	}
+
+	// a broken file gives the defaults and an error
+	if err := os.WriteFile(path, []byte(`{"render_distance": `), 0644); err != nil {
+		t.Fatal(err)
+	}
+	settings, _, err = loadSettings(path)
+	if err == nil || !reflect.DeepEqual(settings, defaultSettings()) {
+		t.Errorf("broken file = %+v, %v, expected the defaults and an error", settings, err)
+	}
+}
+
+func TestSaveSettings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "isometrica", "settings.json")
+	settings := defaultSettings()
+	settings.RenderDistance = 7
+	settings.Debug = true
+	settings.Bindings[ACTION_PLACE] = Binding{Mouse: []string{"Middle"}}
+
+	if err := saveSettings(path, settings); err != nil {
+		t.Fatal(err)
+	}
+	loaded, problems, err := loadSettings(path)
+	if err != nil || len(problems) > 0 {
+		t.Fatalf("failed to load saved settings: %v %v", problems, err)
+	}
+	if !reflect.DeepEqual(loaded, settings) {
+		t.Errorf("loaded %+v, expected %+v", loaded, settings)
+	}
+}
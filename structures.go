@@ -0,0 +1,231 @@
+package main
+
+import (
+	"math/rand"
+)
+
+// STRUCTURES
+// structures are voxel templates that get stamped into the world during generation.
+// placement only depends on the seed and global position, so a structure near a chunk border
+// is placed the same way by every chunk it overlaps, no matter which one is generated first.
+
+// StructureTemplate, a box of voxels that can be stamped into the world.
+// Layers go from the bottom up, each layer is a list of rows along y, and each character in a row is along x.
+type StructureTemplate struct {
+	Layers  [][]string
+	Palette map[rune]string // character to voxel name, characters that aren't in the palette leave the world alone
+	Anchor  [3]int          // the template voxel that sits directly on top of the ground
+}
+
+// PlacementRules, decides where a structure is allowed to go.
+type PlacementRules struct {
+	Ground    []string // blocks the structure can be placed on
+	Biomes    []Biome  // biomes the structure can be placed in, empty is any biome
+	Spacing   int      // the world is split into cells this big, and each cell gets at most one of the structure
+	Chance    float64  // chance of a cell getting the structure
+	Overwrite bool     // replace solid voxels too, not just air and decorations
+}
+
+// Structure, a named template and the rules for placing it.
+// Structures with a Generator build a new template every time they are placed.
+type Structure struct {
+	Name      string
+	Template  StructureTemplate
+	Generator func(srand *rand.Rand) StructureTemplate
+	Reach     int // how far a generated template can reach from its anchor
+	Rules     PlacementRules
+}
+
+// voxels that any structure is allowed to replace
+var structureReplaceable = []string{"Air", "Tall_Grass", "Snowy_Tall_Grass", "Flower", "Snowy_Flower"}
+
+// every structure that can be generated, placed in this order
+var structures = []Structure{
+	{
+		Name:      "Forest_Oak",
+		Generator: oakTree.Generate,
+		Reach:     oakTree.Reach(),
+		Rules:     PlacementRules{Ground: []string{"Grass"}, Biomes: []Biome{BiomeForest}, Spacing: 4, Chance: .75},
+	},
+	{
+		Name:      "Plains_Oak",
+		Generator: oakTree.Generate,
+		Reach:     oakTree.Reach(),
+		Rules:     PlacementRules{Ground: []string{"Grass"}, Biomes: []Biome{BiomePlains}, Spacing: 20, Chance: .35},
+	},
+	{
+		Name:      "Bush",
+		Generator: plainBush.Generate,
+		Reach:     plainBush.Reach(),
+		Rules:     PlacementRules{Ground: []string{"Grass"}, Biomes: []Biome{BiomePlains, BiomeForest}, Spacing: 8, Chance: .4},
+	},
+	{
+		Name:      "Pine",
+		Generator: pineTree.Generate,
+		Reach:     pineTree.Reach(),
+		Rules:     PlacementRules{Ground: []string{"Snowy_Grass"}, Biomes: []Biome{BiomeSnowy}, Spacing: 5, Chance: .65},
+	},
+	{
+		Name: "Cactus",
+		Template: StructureTemplate{
+			Layers:  [][]string{{"C"}, {"C"}, {"C"}},
+			Palette: map[rune]string{'C': "Cactus"},
+		},
+		Rules: PlacementRules{Ground: []string{"Sand"}, Biomes: []Biome{BiomeDesert}, Spacing: 7, Chance: .4},
+	},
+	{
+		Name: "Rock",
+		Template: StructureTemplate{
+			Layers: [][]string{
+				{".C.", "CCC", "CC."},
+				{"...", ".C.", "..."},
+			},
+			Palette: map[rune]string{'C': "Cobblestone"},
+			Anchor:  [3]int{1, 1, 0},
+		},
+		Rules: PlacementRules{Ground: []string{"Grass", "Snowy_Grass", "Stone", "Sand"}, Spacing: 24, Chance: .25},
+	},
+	{
+		Name: "Ruins",
+		Template: StructureTemplate{
+			Layers: [][]string{
+				{"CC.CC", "C...C", ".....", "C...C", "CCC.C"},
+				{"C..C.", "....C", ".....", "C....", "CC..C"},
+				{"C....", ".....", ".....", ".....", "C...."},
+			},
+			Palette: map[rune]string{'C': "Cobblestone"},
+			Anchor:  [3]int{2, 2, 0},
+		},
+		Rules: PlacementRules{Ground: []string{"Grass", "Sand", "Snowy_Grass"}, Spacing: 64, Chance: .2},
+	},
+	{
+		Name: "House",
+		Template: StructureTemplate{
+			Layers: [][]string{
+				{"CCCCC", "CCCCC", "CCCCC", "CCCCC", "CCCCC"},
+				{"WWWWW", "W___W", "W___W", "W___W", "WW_WW"},
+				{"WWWWW", "W___W", "____W", "W___W", "WW_WW"},
+				{"WWWWW", "W___W", "W___W", "W___W", "WWWWW"},
+				{"CCCCC", "CCCCC", "CCCCC", "CCCCC", "CCCCC"},
+			},
+			Palette: map[rune]string{'C': "Cobblestone", 'W': "Wood", '_': "Air"},
+			Anchor:  [3]int{2, 2, 1},
+		},
+		Rules: PlacementRules{Ground: []string{"Grass"}, Biomes: []Biome{BiomePlains, BiomeForest}, Spacing: 96, Chance: .3, Overwrite: true},
+	},
+}
+
+// get the size of a template in voxels
+func (template *StructureTemplate) Size() (width, height, depth int) {
+	depth = len(template.Layers)
+	for _, layer := range template.Layers {
+		height = max(height, len(layer))
+		for _, row := range layer {
+			width = max(width, len([]rune(row)))
+		}
+	}
+	return
+}
+
+// how far the structure can reach from its anchor
+func (structure *Structure) reach() int {
+	if structure.Generator != nil {
+		return structure.Reach
+	}
+	width, height, _ := structure.Template.Size()
+	return max(width, height)
+}
+
+// get the template to place, building a new one if the structure has a generator
+func (structure *Structure) template(srand *rand.Rand) StructureTemplate {
+	if structure.Generator != nil {
+		return structure.Generator(srand)
+	}
+	return structure.Template
+}
+
+// stamp a template into a chunk, with the anchor at a global position.
+// only the part of the template that is inside the chunk is placed.
+func (template *StructureTemplate) Stamp(chunk *Chunk, chunkPosition [2]int, globalX, globalY, z int, overwrite bool) {
+	// template origin in chunk space
+	originX := globalX - template.Anchor[0] - chunkPosition[0]*chunk.Width
+	originY := globalY - template.Anchor[1] - chunkPosition[1]*chunk.Height
+	originZ := z - template.Anchor[2]
+
+	for tz, layer := range template.Layers {
+		for ty, row := range layer {
+			for tx, char := range []rune(row) {
+				name, ok := template.Palette[char]
+				if !ok {
+					continue
+				}
+
+				x, y, z := originX+tx, originY+ty, originZ+tz
+				if !chunk.IsVoxelInBounds(x, y, z) {
+					continue
+				}
+				if !overwrite && !contains(structureReplaceable, chunk.GetVoxel(x, y, z).Name) {
+					continue
+				}
+				chunk.SetVoxel(x, y, z, defaultVoxelDictionary.GetVoxelPointerTo(name))
+			}
+		}
+	}
+}
+
+// work out where a structure goes in a placement cell.
+// the same cell always gives the same answer for the same seed.
+func (world *World) structureInCell(structureIndex, cellX, cellY int) (globalX, globalY, z int, srand *rand.Rand, placed bool) {
+	structure := &structures[structureIndex]
+	rules := &structure.Rules
+
+	srand = rand.New(rand.NewSource(int64(hashCoords(world.Seed, cellX, cellY, structureIndex+1))))
+	if srand.Float64() >= rules.Chance {
+		return 0, 0, 0, nil, false
+	}
+
+	// pick a spot inside the cell
+	globalX = cellX*rules.Spacing + srand.Intn(rules.Spacing)
+	globalY = cellY*rules.Spacing + srand.Intn(rules.Spacing)
+
+	// check the biome
+	if len(rules.Biomes) > 0 && !contains(rules.Biomes, *getBiomeAt(globalX, globalY)) {
+		return 0, 0, 0, nil, false
+	}
+
+	// check the ground
+	groundZ, ground := world.getSurface(globalX, globalY)
+	if !contains(rules.Ground, ground) {
+		return 0, 0, 0, nil, false
+	}
+
+	return globalX, globalY, groundZ + 1, srand, true
+}
+
+// place every structure that overlaps a chunk, including ones that are anchored in neighboring chunks
+func (world *World) placeStructures(chunk *Chunk, position [2]int) {
+	minX, minY := position[0]*chunk.Width, position[1]*chunk.Height
+	maxX, maxY := minX+chunk.Width-1, minY+chunk.Height-1
+
+	for i := range structures {
+		structure := &structures[i]
+		spacing := structure.Rules.Spacing
+		if spacing <= 0 {
+			continue
+		}
+
+		// any structure anchored this far outside the chunk can still reach into it
+		reach := structure.reach()
+
+		for cellX := floorDiv(minX-reach, spacing); cellX <= floorDiv(maxX+reach, spacing); cellX++ {
+			for cellY := floorDiv(minY-reach, spacing); cellY <= floorDiv(maxY+reach, spacing); cellY++ {
+				globalX, globalY, z, srand, placed := world.structureInCell(i, cellX, cellY)
+				if !placed {
+					continue
+				}
+				template := structure.template(srand)
+				template.Stamp(chunk, position, globalX, globalY, z, structure.Rules.Overwrite)
+			}
+		}
+	}
+}
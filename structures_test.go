@@ -0,0 +1,69 @@
+package main
+
+import "testing"
+
+// find a placement of a structure with its anchor this far into a chunk from the west edge
+func findStructureAtChunkEdge(t *testing.T, world *World, name string, offset int) (structureIndex, globalX, globalY, z int) {
+	t.Helper()
+	for i := range structures {
+		if structures[i].Name != name {
+			continue
+		}
+		for cellX := 0; cellX < 300; cellX++ {
+			for cellY := 0; cellY < 300; cellY++ {
+				globalX, globalY, z, _, placed := world.structureInCell(i, cellX, cellY)
+				if placed && globalX-floorDiv(globalX, 32)*32 == offset {
+					return i, globalX, globalY, z
+				}
+			}
+		}
+	}
+	t.Fatalf("no %s found %d from a chunk edge", name, offset)
+	return
+}
+
+// generate two chunks one way round, then the other way round in a new world, and check they come out the same
+func checkChunksMatchInBothOrders(t *testing.T, seed int64, first, second [2]int) (chunks [2]Chunk) {
+	t.Helper()
+	forwards, backwards := World{}, World{}
+	forwards.Initialize(seed)
+	backwards.Initialize(seed)
+	forwards.generateChunk(first, 32, 32, 64, defaultVoxelDictionary)
+	forwards.generateChunk(second, 32, 32, 64, defaultVoxelDictionary)
+	backwards.generateChunk(second, 32, 32, 64, defaultVoxelDictionary)
+	backwards.generateChunk(first, 32, 32, 64, defaultVoxelDictionary)
+
+	for i, position := range [][2]int{first, second} {
+		chunk, other := forwards.Chunks[position], backwards.Chunks[position]
+		for x := 0; x < 32; x++ {
+			for y := 0; y < 32; y++ {
+				for z := 0; z < 64; z++ {
+					if a, b := chunk.GetVoxel(x, y, z).Name, other.GetVoxel(x, y, z).Name; a != b {
+						t.Fatalf("chunk %v at %d, %d, %d is %s one way round and %s the other", position, x, y, z, a, b)
+					}
+				}
+			}
+		}
+		chunks[i] = chunk
+	}
+	return chunks
+}
+
+func TestStructuresMatchAcrossChunkBorders(t *testing.T) {
+	world := World{}
+	world.Initialize(42)
+
+	// a rock on the west edge of a chunk hangs over into the chunk next to it
+	_, globalX, globalY, z := findStructureAtChunkEdge(t, &world, "Rock", 0)
+	east := [2]int{floorDiv(globalX, 32), floorDiv(globalY, 32)}
+	west := [2]int{east[0] - 1, east[1]}
+	chunks := checkChunksMatchInBothOrders(t, 42, east, west)
+
+	localY := globalY - east[1]*32
+	if name := chunks[0].GetVoxel(0, localY, z).Name; name != "Cobblestone" {
+		t.Errorf("rock anchor at %d, %d, %d is %s", globalX, globalY, z, name)
+	}
+	if name := chunks[1].GetVoxel(31, localY, z).Name; name != "Cobblestone" {
+		t.Errorf("rock didn't reach into the chunk to the west, found %s", name)
+	}
+}
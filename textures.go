@@ -28,10 +28,15 @@ var defaultVoxelDictionary = VoxelDictionary{
 		{Name: "Snowy_Tall_Grass", Atlas: groundTextureAtlas, TextureRect: image.Rectangle{Min: image.Point{0, 96}, Max: image.Point{32, 128}}},
 		{Name: "Snowy_Flower", Atlas: groundTextureAtlas, TextureRect: image.Rectangle{Min: image.Point{32, 96}, Max: image.Point{64, 128}}},
 		{Name: "Cactus", Atlas: groundTextureAtlas, TextureRect: image.Rectangle{Min: image.Point{64, 128}, Max: image.Point{96, 160}}},
+		{Name: "Slate", Atlas: groundTextureAtlas, TextureRect: image.Rectangle{Min: image.Point{128, 0}, Max: image.Point{160, 32}}},
+		{Name: "Coal_Ore", Atlas: groundTextureAtlas, TextureRect: image.Rectangle{Min: image.Point{160, 0}, Max: image.Point{192, 32}}},
+		{Name: "Iron_Ore", Atlas: groundTextureAtlas, TextureRect: image.Rectangle{Min: image.Point{192, 0}, Max: image.Point{224, 32}}},
+		{Name: "Gold_Ore", Atlas: groundTextureAtlas, TextureRect: image.Rectangle{Min: image.Point{224, 0}, Max: image.Point{256, 32}}},
 	},
 	Transparent:          []string{"Air", "Water", "Flower", "Snowy_Flower"},
-	Opaque:               []string{"Grass", "Sand", "Stone", "Dirt", "Wood", "Leaves"},
+	Opaque:               []string{"Grass", "Sand", "Stone", "Dirt", "Wood", "Leaves", "Slate", "Coal_Ore", "Iron_Ore", "Gold_Ore"},
 	TransparentNoCulling: []string{"Flower", "Tall_Grass", "Snowy_Tall_Grass"},
+	Ores:                 []string{"Coal_Ore", "Iron_Ore", "Gold_Ore"},
 }
 
 // voxel to be used when an error occurs
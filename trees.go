@@ -0,0 +1,151 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+	"strings"
+)
+
+// TREES
+// trees are structures with a template that is built on the fly from a few parameters,
+// so no two trees of the same kind have to look the same.
+
+type TreeShape int
+
+const (
+	TreeShapeOak  TreeShape = iota // round canopy on a short trunk
+	TreeShapePine                  // cone of leaves around a tall trunk
+	TreeShapeBush                  // a blob of leaves with no trunk showing
+)
+
+// TreeGenerator, the parameters for a kind of tree.
+type TreeGenerator struct {
+	Shape     TreeShape
+	MinHeight int // trunk height
+	MaxHeight int
+	MinRadius int // canopy radius
+	MaxRadius int
+	Trunk     string
+	Leaves    string
+}
+
+// how far a tree can reach from its trunk, used to find trees in neighboring chunks
+func (tree TreeGenerator) Reach() int {
+	return tree.MaxRadius*2 + 1
+}
+
+// build the template for one tree
+func (tree TreeGenerator) Generate(srand *rand.Rand) StructureTemplate {
+	height := tree.MinHeight + srand.Intn(max(1, tree.MaxHeight-tree.MinHeight+1))
+	radius := tree.MinRadius + srand.Intn(max(1, tree.MaxRadius-tree.MinRadius+1))
+	size := radius*2 + 1
+
+	// work out how tall the whole thing is
+	depth := height
+	switch tree.Shape {
+	case TreeShapeOak:
+		depth = height + radius + 1
+	case TreeShapePine:
+		depth = height + 2
+	case TreeShapeBush:
+		depth = max(height, radius+1)
+	}
+
+	// fill a grid with the tree, '.' is left alone
+	grid := make([][][]rune, depth)
+	for z := range grid {
+		grid[z] = make([][]rune, size)
+		for y := range grid[z] {
+			grid[z][y] = []rune(strings.Repeat(".", size))
+		}
+	}
+	setLeaves := func(x, y, z int) {
+		if z >= 0 && z < depth && grid[z][y][x] == '.' {
+			grid[z][y][x] = 'L'
+		}
+	}
+
+	switch tree.Shape {
+	case TreeShapeOak:
+		// a sphere of leaves centered around the top of the trunk, with the corners randomly bitten off
+		centerZ := height - 1
+		for z := 0; z < depth; z++ {
+			for y := 0; y < size; y++ {
+				for x := 0; x < size; x++ {
+					dx, dy, dz := float64(x-radius), float64(y-radius), float64(z-centerZ)*1.3
+					distance := math.Sqrt(dx*dx + dy*dy + dz*dz)
+					if distance <= float64(radius)+.3 && (distance < float64(radius)-.5 || srand.Intn(3) != 0) {
+						setLeaves(x, y, z)
+					}
+				}
+			}
+		}
+	case TreeShapePine:
+		// rings of leaves that get smaller towards the top
+		leavesStart := max(1, height/3)
+		for z := leavesStart; z < depth; z++ {
+			progress := float64(z-leavesStart) / float64(max(1, depth-1-leavesStart))
+			ringRadius := int(math.Round(float64(radius) * (1 - progress)))
+			// every other ring is a bit smaller, which gives the pine its layers
+			if (z-leavesStart)%2 == 1 && ringRadius > 1 {
+				ringRadius--
+			}
+			for y := 0; y < size; y++ {
+				for x := 0; x < size; x++ {
+					if absi(x-radius)+absi(y-radius) <= ringRadius {
+						setLeaves(x, y, z)
+					}
+				}
+			}
+		}
+	case TreeShapeBush:
+		for z := 0; z < depth; z++ {
+			for y := 0; y < size; y++ {
+				for x := 0; x < size; x++ {
+					if absi(x-radius)+absi(y-radius)+z <= radius && srand.Intn(5) != 0 {
+						setLeaves(x, y, z)
+					}
+				}
+			}
+		}
+		// bushes have no trunk showing
+		height = 1
+		grid[0][radius][radius] = 'L'
+	}
+
+	// the trunk goes in last so leaves never cover it
+	if tree.Shape != TreeShapeBush {
+		for z := 0; z < height; z++ {
+			grid[z][radius][radius] = 'W'
+		}
+	}
+
+	// drop empty layers off the top
+	for depth > 1 && strings.Count(string(slices.Concat(grid[depth-1]...)), ".") == size*size {
+		depth--
+	}
+	grid = grid[:depth]
+
+	// convert the grid into layers
+	layers := make([][]string, depth)
+	for z := range grid {
+		layers[z] = make([]string, size)
+		for y := range grid[z] {
+			layers[z][y] = string(grid[z][y])
+		}
+	}
+
+	return StructureTemplate{
+		Layers:  layers,
+		Palette: map[rune]string{'W': tree.Trunk, 'L': tree.Leaves},
+		Anchor:  [3]int{radius, radius, 0},
+	}
+}
+
+// kinds of trees
+var (
+	oakTree   = TreeGenerator{Shape: TreeShapeOak, MinHeight: 3, MaxHeight: 5, MinRadius: 2, MaxRadius: 3, Trunk: "Wood", Leaves: "Leaves"}
+	pineTree  = TreeGenerator{Shape: TreeShapePine, MinHeight: 6, MaxHeight: 9, MinRadius: 2, MaxRadius: 3, Trunk: "Wood", Leaves: "Snowy_Leaves"}
+	plainBush = TreeGenerator{Shape: TreeShapeBush, MinHeight: 1, MaxHeight: 2, MinRadius: 1, MaxRadius: 2, Leaves: "Leaves"}
+)
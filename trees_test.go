@@ -0,0 +1,43 @@
+package main
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestTreesAreTheSameForTheSameSeed(t *testing.T) {
+	for _, tree := range []TreeGenerator{oakTree, pineTree, plainBush} {
+		for seed := int64(0); seed < 20; seed++ {
+			first := tree.Generate(rand.New(rand.NewSource(seed)))
+			second := tree.Generate(rand.New(rand.NewSource(seed)))
+			if !reflect.DeepEqual(first, second) {
+				t.Fatalf("tree shape %d with seed %d came out different twice", tree.Shape, seed)
+			}
+		}
+	}
+}
+
+func TestTreeCanopiesMatchAcrossChunkBorders(t *testing.T) {
+	world := World{}
+	world.Initialize(42)
+
+	// an oak on the west edge of a chunk has leaves in the chunk next to it
+	_, globalX, globalY, z := findStructureAtChunkEdge(t, &world, "Forest_Oak", 0)
+	east := [2]int{floorDiv(globalX, 32), floorDiv(globalY, 32)}
+	west := [2]int{east[0] - 1, east[1]}
+	chunks := checkChunksMatchInBothOrders(t, 42, east, west)
+
+	localY := globalY - east[1]*32
+	leaves := 0
+	for y := max(localY-oakTree.MaxRadius, 0); y <= min(localY+oakTree.MaxRadius, 31); y++ {
+		for tz := z; tz < min(z+oakTree.MaxHeight+oakTree.MaxRadius+1, 64); tz++ {
+			if chunks[1].GetVoxel(31, y, tz).Name == "Leaves" {
+				leaves++
+			}
+		}
+	}
+	if leaves == 0 {
+		t.Errorf("oak at %d, %d, %d didn't reach into the chunk to the west", globalX, globalY, z)
+	}
+}
@@ -1,23 +1,29 @@
 package main
 
 func (game *Game) Update() error {
-	// initiate
-	if !game.HasInitiatedUpdate {
-		game.HasInitiatedUpdate = true
-
-		go game.syncWorldWithDisk()
-	}
-
 	var err error
 
+	game.Input.Update()
+	game.updateNotices()
+
 	// game state
 	switch game.GameState {
 	case GAMESTATE_GAME:
 		err = gameStateUpdateRun(game)
 	case GAMESTATE_TITLE:
 		err = gameStateUpdateTitle(game)
+	case GAMESTATE_WORLDS:
+		err = gameStateUpdateWorlds(game)
 	case GAMESTATE_MENU:
 		err = gameStateUpdateMenu(game)
+	case GAMESTATE_SETTINGS:
+		err = gameStateUpdateSettings(game)
+	case GAMESTATE_CONTROLS:
+		err = gameStateUpdateControls(game)
+	case GAMESTATE_MAP:
+		err = gameStateUpdateMap(game)
+	case GAMESTATE_WAYPOINTS:
+		err = gameStateUpdateWaypoints(game)
 	}
 
 	return err
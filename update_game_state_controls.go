@@ -0,0 +1,55 @@
+package main
+
+// replace the bindings for one kind of input on an action, keeping the others.
+// new slices every time, the old ones may still be shared with the defaults.
+func rebind(bindings map[Action]Binding, action Action, pressed Binding) {
+	binding := bindings[action]
+	switch {
+	case len(pressed.Keys) > 0:
+		binding.Keys = append([]string(nil), pressed.Keys...)
+	case len(pressed.Mouse) > 0:
+		binding.Mouse = append([]string(nil), pressed.Mouse...)
+	case len(pressed.Gamepad) > 0:
+		binding.Gamepad = append([]string(nil), pressed.Gamepad...)
+	}
+	bindings[action] = binding
+}
+
+func gameStateUpdateControls(game *Game) error {
+	action := actions[game.ControlSelection].Action
+
+	// waiting for the player to press whatever they want the action bound to
+	if game.Rebinding {
+		if game.Input.InputJustPressed("Escape") {
+			game.Rebinding = false
+			return nil
+		}
+		if pressed, ok := game.Input.PressedBinding(); ok {
+			rebind(game.Settings.Bindings, action, pressed)
+			game.Rebinding = false
+		}
+		return nil
+	}
+
+	if game.Input.Active(ACTION_MENU_UP) {
+		game.ControlSelection = (game.ControlSelection + len(actions) - 1) % len(actions)
+	}
+	if game.Input.Active(ACTION_MENU_DOWN) {
+		game.ControlSelection = (game.ControlSelection + 1) % len(actions)
+	}
+
+	switch {
+	case game.Input.Active(ACTION_MENU_BACK):
+		game.saveSettings()
+		game.applySettings(false)
+		game.GameState = GAMESTATE_SETTINGS
+	case game.Input.Active(ACTION_MENU_CONFIRM):
+		game.Rebinding = true
+	case game.Input.InputJustPressed("Delete") || game.Input.InputJustPressed("Pad X"):
+		game.Settings.Bindings[action] = Binding{}
+	case game.Input.InputJustPressed("R") || game.Input.InputJustPressed("Pad Y"):
+		game.Settings.Bindings[action] = actions[game.ControlSelection].Default
+	}
+
+	return nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// how many screen pixels a frame the map pans with a key held, or the stick pushed all the way
+const mapPanSpeed = 6
+
+func gameStateUpdateMap(game *Game) error {
+	input := game.Input
+
+	// back to the game, the world keeps loading and saving the whole time
+	if input.Active(ACTION_MAP) || input.Active(ACTION_MENU_BACK) {
+		game.GameState = GAMESTATE_GAME
+		return nil
+	}
+
+	if game.MapScale == 0 {
+		game.MapScale = 1
+	}
+	if input.Active(ACTION_ZOOM_IN) {
+		game.MapScale = mapScales[clampi(indexOf(mapScales, game.MapScale)+1, 0, len(mapScales)-1)]
+	}
+	if input.Active(ACTION_ZOOM_OUT) {
+		game.MapScale = mapScales[clampi(indexOf(mapScales, game.MapScale)-1, 0, len(mapScales)-1)]
+	}
+
+	// pan with the movement keys or the left stick, the map doesn't turn with the camera so up is always -y
+	panX, panY := input.Stick(STICK_LEFT)
+	if input.Active(ACTION_MOVE_FORWARD) {
+		panY -= 1
+	}
+	if input.Active(ACTION_MOVE_BACK) {
+		panY += 1
+	}
+	if input.Active(ACTION_MOVE_LEFT) {
+		panX -= 1
+	}
+	if input.Active(ACTION_MOVE_RIGHT) {
+		panX += 1
+	}
+	game.MapCenter[0] += panX * mapPanSpeed / game.MapScale
+	game.MapCenter[1] += panY * mapPanSpeed / game.MapScale
+
+	// or drag it around with the mouse
+	mouseX, mouseY := ebiten.CursorPosition()
+	if ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
+		game.MapCenter[0] -= float64(mouseX-game.LastMouse[0]) / game.MapScale
+		game.MapCenter[1] -= float64(mouseY-game.LastMouse[1]) / game.MapScale
+	}
+	game.LastMouse = [2]int{mouseX, mouseY}
+
+	// back to the player
+	if input.Active(ACTION_MENU_CONFIRM) {
+		game.MapCenter = [2]float64{float64(game.Player.Position.X), float64(game.Player.Position.Y)}
+	}
+
+	return nil
+}
@@ -1,10 +1,37 @@
 package main
 
-import "github.com/hajimehoshi/ebiten/v2"
+// the pause menu items, in order
+var pauseMenuItems = []string{"Resume", "Waypoints", "Settings", "Save and Quit"}
 
 func gameStateUpdateMenu(game *Game) error {
-	if ebiten.IsKeyPressed(ebiten.KeyEnter) {
+	if game.Input.Active(ACTION_MENU_UP) {
+		game.PauseSelection = (game.PauseSelection + len(pauseMenuItems) - 1) % len(pauseMenuItems)
+	}
+	if game.Input.Active(ACTION_MENU_DOWN) {
+		game.PauseSelection = (game.PauseSelection + 1) % len(pauseMenuItems)
+	}
+
+	if game.Input.Active(ACTION_MENU_BACK) {
+		game.GameState = GAMESTATE_GAME
+		return nil
+	}
+	if !game.Input.Active(ACTION_MENU_CONFIRM) {
+		return nil
+	}
+
+	switch pauseMenuItems[game.PauseSelection] {
+	case "Resume":
 		game.GameState = GAMESTATE_GAME
+	case "Waypoints":
+		game.WaypointSelection, game.WaypointNaming, game.WaypointMessage = 0, false, ""
+		game.GameState = GAMESTATE_WAYPOINTS
+	case "Settings":
+		game.SettingSelection = 0
+		game.GameState = GAMESTATE_SETTINGS
+	case "Save and Quit":
+		game.CloseWorld()
+		game.PauseSelection = 0
+		game.GameState = GAMESTATE_WORLDS
 	}
 
 	return nil
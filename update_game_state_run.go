@@ -1,79 +1,169 @@
 package main
 
-import "github.com/hajimehoshi/ebiten/v2"
+import (
+	"math"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// how many pixels a frame the gamepad cursor moves with the stick pushed all the way
+const gamepadCursorSpeed = 4
+
+// how far the cut-away slice can move from just above the player's head
+const minCutawayOffset, maxCutawayOffset = -8, 16
 
 // listen to inputs
 func runStateInput(game *Game) {
-	// var inputs []string
+	input := game.Input
 
 	var playerSpeed float32 = .05
 
-	// player movement
-	if ebiten.IsKeyPressed(ebiten.KeyW) {
-		game.Player.Velocity.Y -= playerSpeed
-		// inputs = append(inputs, "W")
+	// player movement, worked out on the screen so forward is always up it whichever way the camera faces.
+	// the left stick moves as far as it's pushed.
+	stickX, stickY := input.Stick(STICK_LEFT)
+	moveX, moveY := float32(stickX), float32(stickY)
+	if input.Active(ACTION_MOVE_FORWARD) {
+		moveY -= 1
 	}
-	if ebiten.IsKeyPressed(ebiten.KeyS) {
-		game.Player.Velocity.Y += playerSpeed
-		// inputs = append(inputs, "S")
+	if input.Active(ACTION_MOVE_BACK) {
+		moveY += 1
 	}
-	if ebiten.IsKeyPressed(ebiten.KeyA) {
-		game.Player.Velocity.X -= playerSpeed
-		// inputs = append(inputs, "A")
+	if input.Active(ACTION_MOVE_LEFT) {
+		moveX -= 1
 	}
-	if ebiten.IsKeyPressed(ebiten.KeyD) {
-		game.Player.Velocity.X += playerSpeed
-		// inputs = append(inputs, "D")
+	if input.Active(ACTION_MOVE_RIGHT) {
+		moveX += 1
 	}
-	if ebiten.IsKeyPressed(ebiten.KeySpace) {
+	movement := screenToWorldMovement(game.Direction, moveX, moveY)
+	game.Player.Velocity.X += movement.X * playerSpeed
+	game.Player.Velocity.Y += movement.Y * playerSpeed
+
+	if input.Active(ACTION_JUMP) {
 		game.Player.Velocity.Z += playerSpeed
-		// inputs = append(inputs, "Space")
 	}
-	if ebiten.IsKeyPressed(ebiten.KeyShiftLeft) {
+	if input.Active(ACTION_SNEAK) {
 		game.Player.Velocity.Z -= playerSpeed
-		// inputs = append(inputs, "ShiftLeft")
 	}
 
 	// pause
-	if ebiten.IsKeyPressed(ebiten.KeyEscape) {
+	if input.Active(ACTION_PAUSE) {
 		game.GameState = GAMESTATE_MENU
+		game.SaveWorldInBackground()
 	}
 
 	// toggle depth shift
-	if ebiten.IsKeyPressed(ebiten.KeyBackslash) {
+	if input.Active(ACTION_DEPTH_SHIFT) {
+		game.Settings.DepthShift = !game.Settings.DepthShift
+	}
+	if game.Settings.DepthShift {
 		game.UsingDepthShift = true
-		// inputs = append(inputs, "Backslash")
 	} else {
 		game.UsingDepthShift = false
 		game.DepthShift = 0
 	}
 
 	// toggle debug mode
-	if ebiten.IsKeyPressed(ebiten.KeyF3) {
+	if input.Active(ACTION_DEBUG) {
 		game.DebugMode = !game.DebugMode
-		ebiten.SetVsyncEnabled(!game.DebugMode)
-		// inputs = append(inputs, "F3")
+		game.Settings.Debug = game.DebugMode
+	}
+
+	// cut-away view, the slice moves with the scroll wheel
+	if input.Active(ACTION_CUTAWAY) {
+		game.Cutaway = !game.Cutaway
+	}
+	if game.Cutaway {
+		game.CutawayOffset = clampi(game.CutawayOffset+input.Times(ACTION_CUTAWAY_UP)-input.Times(ACTION_CUTAWAY_DOWN), minCutawayOffset, maxCutawayOffset)
+	}
+
+	// zoom, the logical screen changes size with it in Layout
+	if input.Active(ACTION_ZOOM_IN) {
+		game.Settings.Zoom = stepZoom(game.Settings.Zoom, 1, game.WindowWidth)
+	}
+	if input.Active(ACTION_ZOOM_OUT) {
+		game.Settings.Zoom = stepZoom(game.Settings.Zoom, -1, game.WindowWidth)
+	}
+
+	// screenshots, the normal one is taken when the world is next drawn
+	if input.Active(ACTION_SCREENSHOT) {
+		game.ScreenshotPending = true
+	}
+	if input.Active(ACTION_POSTER) {
+		game.takePoster()
+	}
+
+	// open the full-screen map on the player
+	if input.Active(ACTION_MAP) {
+		game.MapCenter = [2]float64{float64(game.Player.Position.X), float64(game.Player.Position.Y)}
+		game.GameState = GAMESTATE_MAP
+	}
+
+	// toggle x-ray, only in debug mode
+	if game.DebugMode && input.Active(ACTION_XRAY) {
+		game.XRayMode = !game.XRayMode
+	} else if !game.DebugMode {
+		game.XRayMode = false
 	}
 
 	// rotate camera
-	if ebiten.IsKeyPressed(ebiten.KeyUp) {
-		game.Direction = NORTH
-	} else if ebiten.IsKeyPressed(ebiten.KeyDown) {
-		game.Direction = SOUTH
-	} else if ebiten.IsKeyPressed(ebiten.KeyLeft) {
-		game.Direction = WEST
-	} else if ebiten.IsKeyPressed(ebiten.KeyRight) {
-		game.Direction = EAST
-	}
-
-	// if len(inputs) > 0 {
-	// 	log.Printf("Inputs: %s\n", strings.Join(inputs, ", "))
-	// }
+	if input.Active(ACTION_ROTATE_LEFT) {
+		game.Direction = rotateDirection(game.Direction, -1)
+	}
+	if input.Active(ACTION_ROTATE_RIGHT) {
+		game.Direction = rotateDirection(game.Direction, 1)
+	}
+
+	// find the voxel under the cursor, then break or place there
+	game.updateCursor()
+	originX, originY := game.renderOrigin()
+	game.World.readLockChunks()
+	game.Target, game.TargetBefore, game.HasTarget = game.World.pickVoxel(game.Cursor[0], game.Cursor[1], float64(originX), float64(originY), game.Direction, game.cutawayTop())
+	game.World.readUnlockChunks()
+	if game.HasTarget && input.Active(ACTION_BREAK) {
+		if game.World.SetVoxel(game.Target[0], game.Target[1], game.Target[2], defaultVoxelDictionary.GetVoxelPointerTo("Air")) {
+			game.Map.ExploreVoxel(&game.World, game.Target[0], game.Target[1])
+		}
+	}
+	if game.HasTarget && input.Active(ACTION_PLACE) {
+		if game.World.SetVoxel(game.TargetBefore[0], game.TargetBefore[1], game.TargetBefore[2], defaultVoxelDictionary.GetVoxelPointerTo(game.HeldVoxel)) {
+			game.Map.ExploreVoxel(&game.World, game.TargetBefore[0], game.TargetBefore[1])
+		}
+	}
+}
+
+// the right stick moves a cursor of its own, and the mouse takes over again as soon as it moves
+func (game *Game) updateCursor() {
+	mouseX, mouseY := ebiten.CursorPosition()
+	if mouse := [2]int{mouseX, mouseY}; mouse != game.LastMouse {
+		game.LastMouse = mouse
+		game.Cursor = [2]float64{float64(mouseX), float64(mouseY)}
+		game.GamepadCursor = false
+	}
+
+	aimX, aimY := game.Input.Stick(STICK_RIGHT)
+	if aimX != 0 || aimY != 0 {
+		game.GamepadCursor = true
+		game.Cursor[0] = clampf(game.Cursor[0]+aimX*gamepadCursorSpeed, 0, float64(game.ScreenX-1))
+		game.Cursor[1] = clampf(game.Cursor[1]+aimY*gamepadCursorSpeed, 0, float64(game.ScreenY-1))
+	}
 }
 
 func gameStateUpdateRun(game *Game) error {
 	runStateInput(game)
 
+	// arrive once the chunks around a teleport are loaded
+	if game.Teleport != nil {
+		select {
+		case position := <-game.Teleport:
+			// the player's chunk moves with them, so the sync goroutine loads around where they are now
+			game.Player.Position, game.Player.Velocity = position, Vec3{}
+			game.CurrentChunk = positionChunk(position, game.World.ChunkSize)
+			game.Teleport = nil
+		default:
+		}
+	}
+
 	// depth shift
 	if game.UsingDepthShift {
 		if game.DepthShift > 5 {
@@ -90,16 +180,28 @@ func gameStateUpdateRun(game *Game) error {
 	}
 
 	// update player
+	game.World.readLockChunks()
 	game.Player.Update(game.World)
+	game.Player.Animate(game.World, game.Direction, time.Second/time.Duration(ebiten.TPS()))
+	game.World.readUnlockChunks()
 
 	// get current chunk based on player position
-	game.CurrentChunk[0] = int(game.Player.Position.X / float32(game.World.ChunkSize))
-	game.CurrentChunk[1] = int(game.Player.Position.Y / float32(game.World.ChunkSize))
+	cell := game.Player.Cell()
+	game.CurrentChunk[0] = floorDiv(cell[0], game.World.ChunkSize)
+	game.CurrentChunk[1] = floorDiv(cell[1], game.World.ChunkSize)
 
 	// change camera position to have player in the center
-	playerX, playerY := game.Player.getScreenPosition(game.DepthShift)
-	game.Camera[0] = float32(game.ScreenX/2) - playerX
-	game.Camera[1] = float32(game.ScreenY/2) - playerY
+	playerX, playerY := game.Player.screenPosition(game.Direction)
+	game.Camera[0] = float32(math.Round(float64(game.ScreenX/2) - playerX))
+	game.Camera[1] = float32(math.Round(float64(game.ScreenY/2+playerSpriteHeight/2) - playerY))
+
+	// the chunks that can be on screen, which changes with the zoom and the window size
+	originX, originY := game.renderOrigin()
+	game.VisibleChunks = visibleChunks(float64(originX), float64(originY), game.ScreenX, game.ScreenY, game.World.ChunkSize, game.World.ChunkDepth, game.Direction)
+	game.ViewRadius = chunkRadius(game.CurrentChunk, game.VisibleChunks)
+
+	// always load everything that can be on screen, even if the render distance is lower
+	game.setSyncState(SyncState{Center: game.CurrentChunk, Radius: max(game.Settings.RenderDistance, game.ViewRadius), Player: game.Player.ToJSON(), Target: game.TeleportTarget, Arrive: game.Teleport})
 
 	return nil
 }
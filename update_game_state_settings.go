@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// a line in the settings menu, changed with left and right
+type settingItem struct {
+	Name   string
+	Value  func(settings *Settings) string
+	Change func(settings *Settings, step int)
+}
+
+// show a bool setting
+func onOff(value bool) string {
+	if value {
+		return "On"
+	}
+	return "Off"
+}
+
+// every setting in the settings menu, in order
+var settingItems = []settingItem{
+	{
+		Name:   "Render Distance",
+		Value:  func(settings *Settings) string { return fmt.Sprintf("%d chunks", settings.RenderDistance) },
+		Change: func(settings *Settings, step int) { settings.RenderDistance += step },
+	},
+	{
+		Name:   "VSync",
+		Value:  func(settings *Settings) string { return onOff(settings.VSync) },
+		Change: func(settings *Settings, step int) { settings.VSync = !settings.VSync },
+	},
+	{
+		Name:   "Window Scale",
+		Value:  func(settings *Settings) string { return fmt.Sprintf("%dx", settings.WindowScale) },
+		Change: func(settings *Settings, step int) { settings.WindowScale += step },
+	},
+	{
+		Name:   "Depth Shift",
+		Value:  func(settings *Settings) string { return onOff(settings.DepthShift) },
+		Change: func(settings *Settings, step int) { settings.DepthShift = !settings.DepthShift },
+	},
+	{
+		Name: "Cut-Away",
+		Value: func(settings *Settings) string {
+			if settings.CutawayFade {
+				return "Fade"
+			}
+			return "Hide"
+		},
+		Change: func(settings *Settings, step int) { settings.CutawayFade = !settings.CutawayFade },
+	},
+	{
+		Name:   "Screenshot Scale",
+		Value:  func(settings *Settings) string { return fmt.Sprintf("%dx", settings.ScreenshotScale) },
+		Change: func(settings *Settings, step int) { settings.ScreenshotScale += step },
+	},
+	{
+		Name:   "Minimap",
+		Value:  func(settings *Settings) string { return onOff(settings.Minimap) },
+		Change: func(settings *Settings, step int) { settings.Minimap = !settings.Minimap },
+	},
+	{
+		Name:   "Debug Overlay",
+		Value:  func(settings *Settings) string { return onOff(settings.Debug) },
+		Change: func(settings *Settings, step int) { settings.Debug = !settings.Debug },
+	},
+}
+
+// make the game match its settings. the window is only resized when asked,
+// so changing something else doesn't undo the player resizing it by hand.
+func (game *Game) applySettings(resize bool) {
+	ebiten.SetVsyncEnabled(game.Settings.VSync)
+	ebiten.SetTPS(game.Settings.TPS)
+	if resize {
+		ebiten.SetWindowSize(screenWidth*game.Settings.WindowScale, screenHeight*game.Settings.WindowScale)
+	}
+	game.DebugMode = game.Settings.Debug
+	normalizeBindings(game.Settings.Bindings)
+	game.Input.Bindings = game.Settings.Bindings
+}
+
+// write the settings file, if there is somewhere to write it
+func (game *Game) saveSettings() {
+	if game.SettingsPath == "" {
+		return
+	}
+	if err := saveSettings(game.SettingsPath, game.Settings); err != nil {
+		log.Printf("ERROR: Failed to save settings: %v", err)
+	}
+}
+
+func gameStateUpdateSettings(game *Game) error {
+	// after the settings come a line for the controls menu and the way back out
+	controlsLine, backLine := len(settingItems), len(settingItems)+1
+	lines := len(settingItems) + 2
+	if game.Input.Active(ACTION_MENU_UP) {
+		game.SettingSelection = (game.SettingSelection + lines - 1) % lines
+	}
+	if game.Input.Active(ACTION_MENU_DOWN) {
+		game.SettingSelection = (game.SettingSelection + 1) % lines
+	}
+
+	back := game.Input.Active(ACTION_MENU_BACK) ||
+		(game.SettingSelection == backLine && game.Input.Active(ACTION_MENU_CONFIRM))
+	if back {
+		game.saveSettings()
+		game.GameState = GAMESTATE_MENU
+		return nil
+	}
+	if game.SettingSelection == controlsLine {
+		if game.Input.Active(ACTION_MENU_CONFIRM) {
+			game.ControlSelection, game.Rebinding = 0, false
+			game.GameState = GAMESTATE_CONTROLS
+		}
+		return nil
+	}
+	if game.SettingSelection == backLine {
+		return nil
+	}
+
+	step := 0
+	if game.Input.Active(ACTION_MENU_LEFT) {
+		step = -1
+	} else if game.Input.Active(ACTION_MENU_RIGHT) || game.Input.Active(ACTION_MENU_CONFIRM) {
+		step = 1
+	}
+	if step != 0 {
+		item := settingItems[game.SettingSelection]
+		previousScale := game.Settings.WindowScale
+		item.Change(&game.Settings, step)
+		game.Settings.Validate()
+		game.applySettings(game.Settings.WindowScale != previousScale)
+	}
+
+	return nil
+}
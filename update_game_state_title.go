@@ -3,8 +3,8 @@ package main
 import "github.com/hajimehoshi/ebiten/v2"
 
 func gameStateUpdateTitle(game *Game) error {
-	if len(ebiten.InputChars()) > 0 {
-		game.GameState = GAMESTATE_GAME
+	if len(ebiten.InputChars()) > 0 || game.Input.Active(ACTION_MENU_CONFIRM) {
+		game.GameState = GAMESTATE_WORLDS
 	}
 
 	return nil
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"log"
+)
+
+// move the player to a position once the chunks around it are loaded, so they don't land in empty space.
+// the sync goroutine loads them, see syncWorldPass, and the player arrives in gameStateUpdateRun.
+func (game *Game) teleport(position Vec3) {
+	game.Teleport = make(chan Vec3, 1)
+	game.TeleportTarget = position
+
+	// don't wait for the next update or the next sync pass
+	state := game.syncState()
+	state.Target, state.Arrive = position, game.Teleport
+	game.setSyncState(state)
+	game.wakeSync()
+}
+
+// write the waypoints, saying so in the menu if it didn't work
+func (game *Game) saveWaypoints() {
+	if err := saveWaypoints(game.World.SavePath, game.Waypoints); err != nil {
+		log.Printf("ERROR: Failed to save waypoints: %v", err)
+		game.WaypointMessage = fmt.Sprintf("Failed to save waypoints: %v", err)
+	}
+}
+
+func gameStateUpdateWaypoints(game *Game) error {
+	// typing a name for a waypoint where the player is standing
+	if game.WaypointNaming {
+		if game.Input.Active(ACTION_MENU_CONFIRM) {
+			waypoints, err := addWaypoint(game.Waypoints, game.WaypointName, game.Player.Position)
+			if err != nil {
+				game.WaypointMessage = err.Error()
+				return nil
+			}
+			game.Waypoints = waypoints
+			game.WaypointSelection = len(game.Waypoints)
+			game.WaypointMessage = fmt.Sprintf("Added %s", game.Waypoints[len(game.Waypoints)-1].Name)
+			game.WaypointNaming = false
+			game.saveWaypoints()
+		} else if game.Input.InputJustPressed("Escape") {
+			game.WaypointNaming = false
+		} else {
+			game.WaypointName = game.typeInto(game.WaypointName)
+		}
+		return nil
+	}
+
+	// adding one comes first, then the waypoints, then the way back out
+	addLine, backLine := 0, len(game.Waypoints)+1
+	lines := len(game.Waypoints) + 2
+	if game.Input.Active(ACTION_MENU_UP) {
+		game.WaypointSelection = (game.WaypointSelection + lines - 1) % lines
+	}
+	if game.Input.Active(ACTION_MENU_DOWN) {
+		game.WaypointSelection = (game.WaypointSelection + 1) % lines
+	}
+
+	if game.Input.Active(ACTION_MENU_BACK) || (game.WaypointSelection == backLine && game.Input.Active(ACTION_MENU_CONFIRM)) {
+		game.GameState = GAMESTATE_MENU
+		return nil
+	}
+	if game.WaypointSelection == addLine {
+		if game.Input.Active(ACTION_MENU_CONFIRM) {
+			game.WaypointNaming, game.WaypointName, game.WaypointMessage = true, "", ""
+		}
+		return nil
+	}
+	if game.WaypointSelection == backLine {
+		return nil
+	}
+
+	index := game.WaypointSelection - 1
+	switch {
+	case game.Input.Active(ACTION_MENU_CONFIRM):
+		if game.Teleport != nil {
+			game.WaypointMessage = "Already teleporting"
+			return nil
+		}
+		game.teleport(game.Waypoints[index].Vec3())
+		game.GameState = GAMESTATE_GAME
+	case game.Input.InputJustPressed("Delete") || game.Input.InputJustPressed("Pad X"):
+		game.WaypointMessage = fmt.Sprintf("Removed %s", game.Waypoints[index].Name)
+		game.Waypoints = removeWaypoint(game.Waypoints, index)
+		game.WaypointSelection = min(game.WaypointSelection, len(game.Waypoints))
+		game.saveWaypoints()
+	}
+
+	return nil
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand/v2"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+)
+
+// what the world list is doing
+type worldMenuMode int
+
+const (
+	WORLDMENU_LIST worldMenuMode = iota
+	WORLDMENU_CREATE
+	WORLDMENU_RENAME
+	WORLDMENU_DUPLICATE
+	WORLDMENU_DELETE
+	WORLDMENU_BACKUPS
+	WORLDMENU_RESTORE
+)
+
+// longest name that can be typed in
+const worldNameMaxLength = 32
+
+// WorldMenu, the state of the world list screen.
+type WorldMenu struct {
+	Worlds      []WorldInfo
+	Selected    int
+	Mode        worldMenuMode
+	NameInput   string
+	SeedInput   string
+	EditingSeed bool   // typing goes into the seed instead of the name
+	Message     string // last error or result, shown at the bottom
+	Loaded      bool   // Worlds is up to date
+
+	Backups        []BackupInfo // backups of the selected world, when looking at them
+	SelectedBackup int
+
+	Previews map[string]*ebiten.Image // preview images by save path, nil if there isn't one
+}
+
+// read the world list again, keeping the same world selected if it's still there
+func (menu *WorldMenu) refresh() {
+	selectedPath := ""
+	if menu.Selected < len(menu.Worlds) {
+		selectedPath = menu.Worlds[menu.Selected].Path
+	}
+
+	worlds, err := listWorlds(savesPath)
+	if err != nil {
+		menu.Message = fmt.Sprintf("Failed to list worlds: %v", err)
+	}
+	menu.Worlds = worlds
+	menu.Selected = 0
+	for i, world := range worlds {
+		if world.Path == selectedPath {
+			menu.Selected = i
+		}
+	}
+	menu.Previews = make(map[string]*ebiten.Image)
+	menu.Loaded = true
+}
+
+// get the preview of a world, loading it the first time
+func (menu *WorldMenu) preview(path string) *ebiten.Image {
+	if preview, ok := menu.Previews[path]; ok {
+		return preview
+	}
+	preview, _, err := ebitenutil.NewImageFromFile(filepath.Join(path, "preview.png"))
+	if err != nil {
+		preview = nil
+	}
+	menu.Previews[path] = preview
+	return preview
+}
+
+// turn whatever was typed into the seed box into a seed, random if it's empty
+func parseSeed(input string) int64 {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return rand.Int64()
+	}
+	if seed, err := strconv.ParseInt(input, 10, 64); err == nil {
+		return seed
+	}
+	// words make a seed too
+	hash := fnv.New64a()
+	hash.Write([]byte(input))
+	return int64(hash.Sum64())
+}
+
+// add typed characters to a text box, dropping anything the font can't draw
+func (game *Game) typeInto(text string) string {
+	for _, char := range ebiten.AppendInputChars(nil) {
+		if strings.ContainsRune(game.Font.CharSet, char) && len([]rune(text)) < worldNameMaxLength {
+			text += string(char)
+		}
+	}
+	if game.Input.InputJustPressed("Backspace") && len(text) > 0 {
+		runes := []rune(text)
+		text = string(runes[:len(runes)-1])
+	}
+	return text
+}
+
+// a yes or no question was answered yes, with Y or confirm
+func (game *Game) confirmed() bool {
+	return game.Input.InputJustPressed("Y") || game.Input.Active(ACTION_MENU_CONFIRM)
+}
+
+// a yes or no question was answered no, with N or back
+func (game *Game) cancelled() bool {
+	return game.Input.InputJustPressed("N") || game.Input.Active(ACTION_MENU_BACK)
+}
+
+func gameStateUpdateWorlds(game *Game) error {
+	menu := &game.WorldMenu
+	if !menu.Loaded {
+		menu.refresh()
+	}
+
+	switch menu.Mode {
+	case WORLDMENU_LIST:
+		worldsStateListInput(game, menu)
+
+	case WORLDMENU_CREATE, WORLDMENU_RENAME, WORLDMENU_DUPLICATE:
+		if menu.EditingSeed {
+			menu.SeedInput = game.typeInto(menu.SeedInput)
+		} else {
+			menu.NameInput = game.typeInto(menu.NameInput)
+		}
+		if menu.Mode == WORLDMENU_CREATE && game.Input.InputJustPressed("Tab") {
+			menu.EditingSeed = !menu.EditingSeed
+		}
+		if game.Input.Active(ACTION_MENU_BACK) {
+			menu.Mode = WORLDMENU_LIST
+		}
+		if game.Input.Active(ACTION_MENU_CONFIRM) {
+			worldsStateConfirmInput(menu)
+		}
+
+	case WORLDMENU_DELETE:
+		if game.confirmed() {
+			world := menu.Worlds[menu.Selected]
+			if err := deleteWorld(world.Path); err != nil {
+				menu.Message = fmt.Sprintf("Failed to delete world: %v", err)
+			} else {
+				menu.Message = fmt.Sprintf("Deleted %s", world.Name)
+			}
+			menu.Mode = WORLDMENU_LIST
+			menu.refresh()
+		} else if game.cancelled() {
+			menu.Mode = WORLDMENU_LIST
+		}
+
+	case WORLDMENU_BACKUPS:
+		if game.Input.Active(ACTION_MENU_UP) && menu.SelectedBackup > 0 {
+			menu.SelectedBackup--
+		}
+		if game.Input.Active(ACTION_MENU_DOWN) && menu.SelectedBackup < len(menu.Backups)-1 {
+			menu.SelectedBackup++
+		}
+		if game.Input.Active(ACTION_MENU_CONFIRM) && len(menu.Backups) > 0 {
+			menu.Mode = WORLDMENU_RESTORE
+		}
+		if game.Input.Active(ACTION_MENU_BACK) {
+			menu.Mode = WORLDMENU_LIST
+		}
+
+	case WORLDMENU_RESTORE:
+		if game.confirmed() {
+			world, backup := menu.Worlds[menu.Selected], menu.Backups[menu.SelectedBackup]
+			if err := restoreBackup(world.Path, backup.Path); err != nil {
+				menu.Message = fmt.Sprintf("Failed to restore backup: %v", err)
+			} else {
+				menu.Message = fmt.Sprintf("Restored %s from %s", world.Name, backup.Time.Format("2006-01-02 15:04"))
+			}
+			menu.Mode = WORLDMENU_LIST
+			menu.refresh()
+		} else if game.cancelled() {
+			menu.Mode = WORLDMENU_BACKUPS
+		}
+	}
+
+	return nil
+}
+
+// moving around the list and picking what to do with a world
+func worldsStateListInput(game *Game, menu *WorldMenu) {
+	if game.Input.Active(ACTION_MENU_UP) && menu.Selected > 0 {
+		menu.Selected--
+	}
+	if game.Input.Active(ACTION_MENU_DOWN) && menu.Selected < len(menu.Worlds)-1 {
+		menu.Selected++
+	}
+	if game.Input.Active(ACTION_MENU_BACK) {
+		game.GameState = GAMESTATE_TITLE
+		return
+	}
+
+	// new world
+	if game.Input.InputJustPressed("N") || game.Input.InputJustPressed("Pad X") {
+		menu.Mode = WORLDMENU_CREATE
+		menu.NameInput, menu.SeedInput, menu.EditingSeed = "New World", "", false
+		return
+	}
+
+	// everything else needs a world
+	if len(menu.Worlds) == 0 {
+		return
+	}
+	world := menu.Worlds[menu.Selected]
+
+	switch {
+	case game.Input.Active(ACTION_MENU_CONFIRM):
+		if err := game.OpenWorld(world.Path); err != nil {
+			menu.Message = fmt.Sprintf("Failed to open world: %v", err)
+			return
+		}
+		menu.Message = ""
+		menu.Loaded = false
+		game.GameState = GAMESTATE_GAME
+	case game.Input.InputJustPressed("R"):
+		menu.Mode = WORLDMENU_RENAME
+		menu.NameInput, menu.EditingSeed = world.Name, false
+	case game.Input.InputJustPressed("C") || game.Input.InputJustPressed("Pad LeftShoulder"):
+		menu.Mode = WORLDMENU_DUPLICATE
+		menu.NameInput, menu.EditingSeed = world.Name+" Copy", false
+	case game.Input.InputJustPressed("Delete") || game.Input.InputJustPressed("Pad Back"):
+		menu.Mode = WORLDMENU_DELETE
+	case game.Input.InputJustPressed("B") || game.Input.InputJustPressed("Pad RightShoulder"):
+		if backupPath, err := createBackup(world.Path, "manual"); err != nil {
+			menu.Message = fmt.Sprintf("Failed to back up world: %v", err)
+		} else {
+			menu.Message = fmt.Sprintf("Backed up to %s", backupPath)
+		}
+	case game.Input.InputJustPressed("S") || game.Input.InputJustPressed("Pad Y"):
+		backups, err := listBackups(world.Path)
+		if err != nil {
+			menu.Message = fmt.Sprintf("Failed to list backups: %v", err)
+			return
+		}
+		menu.Backups, menu.SelectedBackup = backups, 0
+		menu.Mode = WORLDMENU_BACKUPS
+	}
+}
+
+// do whatever the text boxes were for
+func worldsStateConfirmInput(menu *WorldMenu) {
+	var err error
+	switch menu.Mode {
+	case WORLDMENU_CREATE:
+		var path string
+		path, err = createWorld(savesPath, menu.NameInput, parseSeed(menu.SeedInput))
+		if err == nil {
+			menu.Message = fmt.Sprintf("Created %s", strings.TrimSpace(menu.NameInput))
+			// select the new world
+			menu.Worlds = []WorldInfo{{Path: path}}
+			menu.Selected = 0
+		}
+	case WORLDMENU_RENAME:
+		err = renameWorld(menu.Worlds[menu.Selected].Path, menu.NameInput)
+		if err == nil {
+			menu.Message = fmt.Sprintf("Renamed to %s", strings.TrimSpace(menu.NameInput))
+		}
+	case WORLDMENU_DUPLICATE:
+		_, err = duplicateWorld(savesPath, menu.Worlds[menu.Selected].Path, menu.NameInput)
+		if err == nil {
+			menu.Message = fmt.Sprintf("Duplicated as %s", strings.TrimSpace(menu.NameInput))
+		}
+	}
+
+	if err != nil {
+		// stay in the text box so it can be fixed
+		menu.Message = err.Error()
+		return
+	}
+	menu.Mode = WORLDMENU_LIST
+	menu.refresh()
+}
@@ -0,0 +1,92 @@
+package main
+
+import "math"
+
+// VIEW
+// works out which chunks can be on the screen, from where the screen is rather than a fixed distance
+// around the player, so any window size, zoom, chunk size or chunk depth draws everything it needs and nothing more.
+
+// how far past the edge of the screen a chunk still counts as on it, for the depth shift wobble
+const viewMargin = 8
+
+// the screen rectangle a chunk's voxels are drawn inside, relative to where voxel 0, 0, 0 is drawn
+func chunkScreenBounds(key [2]int, chunkSize, chunkDepth int, direction [4]int) (minX, minY, maxX, maxY int) {
+	minX, minY = math.MaxInt, math.MaxInt
+	maxX, maxY = math.MinInt, math.MinInt
+	for _, x := range []int{key[0] * chunkSize, key[0]*chunkSize + chunkSize - 1} {
+		for _, y := range []int{key[1] * chunkSize, key[1]*chunkSize + chunkSize - 1} {
+			for _, z := range []int{0, chunkDepth - 1} {
+				screenX, screenY := getScreenPosition(x, y, z, 0, 0, 0, direction)
+				minX, minY = min(minX, screenX), min(minY, screenY)
+				maxX, maxY = max(maxX, screenX+tileWidth), max(maxY, screenY+tileHeight)
+			}
+		}
+	}
+	return
+}
+
+// can any of a chunk be on the screen
+func chunkOnScreen(key [2]int, originX, originY float64, screenWidth, screenHeight, chunkSize, chunkDepth int, direction [4]int) bool {
+	minX, minY, maxX, maxY := chunkScreenBounds(key, chunkSize, chunkDepth, direction)
+	left, top := int(math.Floor(originX)), int(math.Floor(originY))
+	return left+maxX > -viewMargin && left+minX < screenWidth+viewMargin &&
+		top+maxY > -viewMargin && top+minY < screenHeight+viewMargin
+}
+
+// every chunk that can be on the screen, in the order they have to be drawn in
+func visibleChunks(originX, originY float64, screenWidth, screenHeight, chunkSize, chunkDepth int, direction [4]int) [][2]int {
+	sxX, sxY, syX, syY := float64(direction[0]), float64(direction[1]), float64(direction[2]), float64(direction[3])
+	det := sxX*syY - syX*sxY
+
+	// undo the projection at the corners of the screen, at the bottom and top of the world,
+	// to get the range of voxels that could be drawn there. tiles are drawn down and right of their position.
+	left, top := float64(-viewMargin-tileWidth), float64(-viewMargin-tileHeight)
+	right, bottom := float64(screenWidth+viewMargin), float64(screenHeight+viewMargin)
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, corner := range [][2]float64{{left, top}, {right, top}, {left, bottom}, {right, bottom}} {
+		for _, z := range []float64{0, float64(chunkDepth)} {
+			a := (corner[0] - originX) / (float64(v) / 2)
+			b := (corner[1] - originY + z*float64(v)/2) / (float64(v) / 4)
+			x, y := (syY*a-syX*b)/det, (sxX*b-sxY*a)/det
+			minX, minY = math.Min(minX, x), math.Min(minY, y)
+			maxX, maxY = math.Max(maxX, x), math.Max(maxY, y)
+		}
+	}
+
+	// a chunk either side, the box a chunk is drawn in sticks out past the chunk itself
+	chunkMinX := floorDiv(int(math.Floor(minX)), chunkSize) - 1
+	chunkMaxX := floorDiv(int(math.Ceil(maxX)), chunkSize) + 1
+	chunkMinY := floorDiv(int(math.Floor(minY)), chunkSize) - 1
+	chunkMaxY := floorDiv(int(math.Ceil(maxY)), chunkSize) + 1
+
+	// walk them in the same order as the voxels in a chunk
+	stepX, stepY := renderStep(direction)
+	startX, stopX := chunkMinX, chunkMaxX+1
+	if stepX < 0 {
+		startX, stopX = chunkMaxX, chunkMinX-1
+	}
+	startY, stopY := chunkMinY, chunkMaxY+1
+	if stepY < 0 {
+		startY, stopY = chunkMaxY, chunkMinY-1
+	}
+
+	var chunks [][2]int
+	for x := startX; x != stopX; x += stepX {
+		for y := startY; y != stopY; y += stepY {
+			if chunkOnScreen([2]int{x, y}, originX, originY, screenWidth, screenHeight, chunkSize, chunkDepth, direction) {
+				chunks = append(chunks, [2]int{x, y})
+			}
+		}
+	}
+	return chunks
+}
+
+// how many chunks out from a chunk the furthest of some chunks is
+func chunkRadius(center [2]int, chunks [][2]int) int {
+	radius := 0
+	for _, key := range chunks {
+		radius = max(radius, absi(key[0]-center[0]), absi(key[1]-center[1]))
+	}
+	return radius
+}
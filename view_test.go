@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestVisibleChunks(t *testing.T) {
+	tests := []struct {
+		screenWidth, screenHeight int
+		chunkSize, chunkDepth     int
+		originX, originY          float64
+	}{
+		{640, 360, 32, 64, 320, 180},
+		{1280, 720, 32, 64, -5000, 2000},
+		{320, 180, 16, 128, 160, 90},
+		{2560, 1440, 8, 16, 1280, 700},
+		{100, 1000, 64, 32, 50, -300},
+	}
+	for _, test := range tests {
+		for _, direction := range directions {
+			name := fmt.Sprintf("%dx%d %d/%d %s", test.screenWidth, test.screenHeight, test.chunkSize, test.chunkDepth, directionName(direction))
+			chunks := visibleChunks(test.originX, test.originY, test.screenWidth, test.screenHeight, test.chunkSize, test.chunkDepth, direction)
+			if len(chunks) == 0 {
+				t.Errorf("%s: no chunks", name)
+				continue
+			}
+
+			// the same chunks as checking every chunk for miles around
+			got := map[[2]int]bool{}
+			for _, key := range chunks {
+				if got[key] {
+					t.Errorf("%s: %v listed twice", name, key)
+				}
+				got[key] = true
+			}
+			for x := -200; x <= 200; x++ {
+				for y := -200; y <= 200; y++ {
+					key := [2]int{x, y}
+					if chunkOnScreen(key, test.originX, test.originY, test.screenWidth, test.screenHeight, test.chunkSize, test.chunkDepth, direction) != got[key] {
+						t.Errorf("%s: %v on screen is %v, but visibleChunks says %v", name, key, !got[key], got[key])
+					}
+				}
+			}
+
+			// in painter's order, nothing drawn before a chunk it's in front of
+			stepX, stepY := renderStep(direction)
+			for i := 1; i < len(chunks); i++ {
+				a, b := chunks[i-1], chunks[i]
+				if (b[0]-a[0])*stepX < 0 || (b[0] == a[0] && (b[1]-a[1])*stepY <= 0) {
+					t.Errorf("%s: %v drawn before %v", name, a, b)
+				}
+			}
+		}
+	}
+}
+
+func TestChunkRadius(t *testing.T) {
+	chunks := [][2]int{{0, 0}, {2, -1}, {-1, 3}, {1, 1}}
+	if radius := chunkRadius([2]int{0, 0}, chunks); radius != 3 {
+		t.Errorf("chunkRadius = %d, want 3", radius)
+	}
+	if radius := chunkRadius([2]int{0, 0}, nil); radius != 0 {
+		t.Errorf("chunkRadius of nothing = %d, want 0", radius)
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// WAYPOINTS
+// named places in a world, kept in waypoints.json next to player.json.
+// they're marked in game and on the map, and the waypoints menu teleports to them.
+
+// Waypoint, a named place. the position is stored the same way as the player's.
+type Waypoint struct {
+	Name     string     `json:"name"`
+	Position [3]float32 `json:"position"`
+}
+
+// json waypoints
+type WaypointsJSON struct {
+	Waypoints []Waypoint `json:"waypoints"`
+}
+
+// where the waypoint is, as a Vec3
+func (waypoint Waypoint) Vec3() Vec3 {
+	return Vec3{waypoint.Position[0], waypoint.Position[1], waypoint.Position[2]}
+}
+
+// how many voxels away from a position the waypoint is
+func (waypoint Waypoint) Distance(from Vec3) float64 {
+	x, y, z := float64(waypoint.Position[0]-from.X), float64(waypoint.Position[1]-from.Y), float64(waypoint.Position[2]-from.Z)
+	return math.Sqrt(x*x + y*y + z*z)
+}
+
+// read a world's waypoints, a world without a waypoints file just doesn't have any
+func loadWaypoints(savePath string) (waypoints []Waypoint, err error) {
+	data, err := os.ReadFile(filepath.Join(savePath, "waypoints.json"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var waypointsJSON WaypointsJSON
+	if err = json.Unmarshal(data, &waypointsJSON); err != nil {
+		return nil, fmt.Errorf("waypoints.json: %v", err)
+	}
+	return waypointsJSON.Waypoints, nil
+}
+
+// write a world's waypoints
+func saveWaypoints(savePath string, waypoints []Waypoint) error {
+	data, err := json.MarshalIndent(WaypointsJSON{Waypoints: waypoints}, "", "\t")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(savePath, "waypoints.json"), data, 0644)
+}
+
+// find a waypoint by name, case insensitive
+func findWaypoint(waypoints []Waypoint, name string) (index int, ok bool) {
+	for i, waypoint := range waypoints {
+		if strings.EqualFold(waypoint.Name, strings.TrimSpace(name)) {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+// add a waypoint at a position. names have to be different from each other,
+// and a waypoint with no name gets the first free "Waypoint N".
+func addWaypoint(waypoints []Waypoint, name string, position Vec3) ([]Waypoint, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		for n := len(waypoints) + 1; ; n++ {
+			name = fmt.Sprintf("Waypoint %d", n)
+			if _, taken := findWaypoint(waypoints, name); !taken {
+				break
+			}
+		}
+	}
+	if _, taken := findWaypoint(waypoints, name); taken {
+		return waypoints, fmt.Errorf("there is already a waypoint called %s", name)
+	}
+	return append(waypoints, Waypoint{Name: name, Position: [3]float32{position.X, position.Y, position.Z}}), nil
+}
+
+// take a waypoint out of the list, without touching the list it came from
+func removeWaypoint(waypoints []Waypoint, index int) []Waypoint {
+	if index < 0 || index >= len(waypoints) {
+		return waypoints
+	}
+	return append(waypoints[:index:index], waypoints[index+1:]...)
+}
+
+// the chunk a position is in, the same way as the player's chunk
+func positionChunk(position Vec3, chunkSize int) [2]int {
+	return [2]int{
+		floorDiv(int(math.Floor(float64(position.X)+.5)), chunkSize),
+		floorDiv(int(math.Floor(float64(position.Y)+.5)), chunkSize),
+	}
+}
+
+// the chunks within radius of a position, nearest first, so a teleport loads where the player lands before anything else
+func teleportChunks(position Vec3, chunkSize, radius int) [][2]int {
+	center := positionChunk(position, chunkSize)
+	var chunks [][2]int
+	for x := center[0] - radius; x <= center[0]+radius; x++ {
+		for y := center[1] - radius; y <= center[1]+radius; y++ {
+			chunks = append(chunks, [2]int{x, y})
+		}
+	}
+	ring := func(key [2]int) int {
+		return max(absi(key[0]-center[0]), absi(key[1]-center[1]))
+	}
+	sort.SliceStable(chunks, func(i, j int) bool {
+		return ring(chunks[i]) < ring(chunks[j])
+	})
+	return chunks
+}
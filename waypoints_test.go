@@ -0,0 +1,137 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAddAndRemoveWaypoints(t *testing.T) {
+	var waypoints []Waypoint
+	waypoints, err := addWaypoint(waypoints, "  Home ", Vec3{1, 2, 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if waypoints[0].Name != "Home" || waypoints[0].Vec3() != (Vec3{1, 2, 3}) {
+		t.Errorf("added %+v", waypoints[0])
+	}
+
+	// names are case insensitive, so this is the same one
+	if _, err := addWaypoint(waypoints, "home", Vec3{}); err == nil {
+		t.Errorf("added a second waypoint called home")
+	}
+
+	// no name gets a number, skipping ones that are taken
+	waypoints, _ = addWaypoint(waypoints, "Waypoint 2", Vec3{})
+	waypoints, err = addWaypoint(waypoints, "", Vec3{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name := waypoints[2].Name; name != "Waypoint 3" {
+		t.Errorf("unnamed waypoint is called %s, expected Waypoint 3", name)
+	}
+
+	if index, ok := findWaypoint(waypoints, "WAYPOINT 2"); !ok || index != 1 {
+		t.Errorf("found Waypoint 2 at %d (%v)", index, ok)
+	}
+
+	// removing doesn't change the list it came from
+	removed := removeWaypoint(waypoints, 1)
+	if len(removed) != 2 || removed[0].Name != "Home" || removed[1].Name != "Waypoint 3" {
+		t.Errorf("removed the wrong waypoint: %+v", removed)
+	}
+	if waypoints[1].Name != "Waypoint 2" {
+		t.Errorf("removing changed the original list: %+v", waypoints)
+	}
+	if len(removeWaypoint(waypoints, 7)) != 3 {
+		t.Errorf("removing a waypoint that isn't there removed one")
+	}
+}
+
+func TestSaveAndLoadWaypoints(t *testing.T) {
+	savePath := t.TempDir()
+
+	// a world without a waypoints file has none
+	waypoints, err := loadWaypoints(savePath)
+	if err != nil || len(waypoints) != 0 {
+		t.Fatalf("loaded %v (%v) from a world without waypoints", waypoints, err)
+	}
+
+	waypoints, _ = addWaypoint(nil, "Home", Vec3{1.5, -2, 30})
+	waypoints, _ = addWaypoint(waypoints, "Mine", Vec3{-400, 12, 8})
+	if err := saveWaypoints(savePath, waypoints); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := loadWaypoints(savePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, waypoints) {
+		t.Errorf("loaded %+v, expected %+v", loaded, waypoints)
+	}
+
+	if err := os.WriteFile(filepath.Join(savePath, "waypoints.json"), []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadWaypoints(savePath); err == nil {
+		t.Errorf("loaded a broken waypoints file")
+	}
+}
+
+func TestWaypointDistance(t *testing.T) {
+	waypoint := Waypoint{Position: [3]float32{3, 4, 12}}
+	if distance := waypoint.Distance(Vec3{}); distance != 13 {
+		t.Errorf("distance = %g, expected 13", distance)
+	}
+}
+
+func TestTeleportChunks(t *testing.T) {
+	chunks := teleportChunks(Vec3{-40, 70, 10}, 32, 2)
+	if len(chunks) != 25 {
+		t.Fatalf("got %d chunks, expected 25", len(chunks))
+	}
+	// the chunk the player lands in comes first, then the ones around it
+	if chunks[0] != [2]int{-2, 2} {
+		t.Errorf("first chunk is %v, expected -2, 2", chunks[0])
+	}
+	for _, key := range chunks[1:9] {
+		if absi(key[0]+2) > 1 || absi(key[1]-2) > 1 {
+			t.Errorf("%v loaded before the chunks next to where the player lands", key)
+		}
+	}
+}
+
+func TestTeleportLoadsTargetFirst(t *testing.T) {
+	savePath, err := createWorld(t.TempDir(), "Far Away", 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	game := &Game{Settings: defaultSettings()}
+	game.Settings.RenderDistance = minRenderDistance
+	if err := game.OpenWorld(savePath); err != nil {
+		t.Fatalf("failed to open world: %v", err)
+	}
+	defer game.CloseWorld()
+
+	start := game.CurrentChunk
+	position := Vec3{float32(10 * game.World.ChunkSize), 0, 40}
+	game.teleport(position)
+
+	select {
+	case arrived := <-game.Teleport:
+		if arrived != position {
+			t.Errorf("arrived at %v, expected %v", arrived, position)
+		}
+	case <-time.After(30 * time.Second):
+		t.Fatalf("never arrived")
+	}
+
+	// both where the player was and where they're going stay loaded until they arrive
+	for _, key := range [][2]int{start, positionChunk(position, game.World.ChunkSize)} {
+		if !game.World.chunkLoaded(key) {
+			t.Errorf("chunk %v isn't loaded", key)
+		}
+	}
+}
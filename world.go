@@ -2,6 +2,9 @@ package main
 
 import (
 	"image"
+	"slices"
+	"sync"
+	"time"
 
 	"github.com/aquilax/go-perlin"
 	"github.com/hajimehoshi/ebiten/v2"
@@ -13,6 +16,7 @@ type VoxelDictionary struct {
 	Transparent          []string
 	TransparentNoCulling []string
 	Opaque               []string
+	Ores                 []string
 }
 
 // get a []string of voxels that are transparent
@@ -79,6 +83,8 @@ type Chunk struct {
 	Width  int
 	Height int
 	Depth  int
+	Dirty  bool // changed since it was generated or loaded, so it needs to be saved
+	Edits  int  // goes up with every change, so a save knows if the chunk changed while it was being written
 }
 
 // Get voxel at x, y, z
@@ -137,27 +143,110 @@ func MakeChunk(voxels [][][]VoxelPointer) Chunk {
 // World, stores chunks in a map
 type World struct {
 	Chunks                 map[[2]int]Chunk
+	ChunksMutex            *sync.RWMutex // guards Chunks, see lockChunks
 	Seed                   int64
 	PerlinNoise            *perlin.Perlin
+	RiverNoise             *perlin.Perlin
+	OreNoise               *perlin.Perlin
 	WaterLevel             int
 	SurfaceFeaturesBeginAt int
 	ChunkSize              int
 	ChunkDepth             int
 	SavePath               string
+	Name                   string    // shown in the world list
+	LastPlayed             time.Time // when the world was last opened
 	Initiated              bool
 }
 
-// Return a Chunk from the world
+// the sync goroutine loads and unloads chunks while the game loop edits and draws them,
+// so anything that writes to Chunks takes lockChunks and anything that reads it takes readLockChunks.
+// a world that was never initialized, like the ones tests make, has no mutex and these do nothing.
+func (w *World) lockChunks() {
+	if w.ChunksMutex != nil {
+		w.ChunksMutex.Lock()
+	}
+}
+
+func (w *World) unlockChunks() {
+	if w.ChunksMutex != nil {
+		w.ChunksMutex.Unlock()
+	}
+}
+
+func (w *World) readLockChunks() {
+	if w.ChunksMutex != nil {
+		w.ChunksMutex.RLock()
+	}
+}
+
+func (w *World) readUnlockChunks() {
+	if w.ChunksMutex != nil {
+		w.ChunksMutex.RUnlock()
+	}
+}
+
+// add a chunk to the world, or replace it
+func (w *World) storeChunk(position [2]int, chunk Chunk) {
+	w.lockChunks()
+	defer w.unlockChunks()
+	w.Chunks[position] = chunk
+}
+
+// is a chunk loaded
+func (w *World) chunkLoaded(position [2]int) bool {
+	w.readLockChunks()
+	defer w.readUnlockChunks()
+	_, exists := w.Chunks[position]
+	return exists
+}
+
+// copy the world for reading in the background. voxels are copied too,
+// since SetVoxel changes them in place, and nothing else uses the copy so it has no lock.
+func (w *World) snapshot() World {
+	w.readLockChunks()
+	defer w.readUnlockChunks()
+	snapshot := *w
+	snapshot.ChunksMutex = nil
+	snapshot.Chunks = make(map[[2]int]Chunk, len(w.Chunks))
+	for key, chunk := range w.Chunks {
+		chunk.Voxels = slices.Clone(chunk.Voxels)
+		snapshot.Chunks[key] = chunk
+	}
+	return snapshot
+}
+
+// Return a Chunk from the world, the caller holds readLockChunks
 func (w *World) GetChunk(x, y int) (chunk Chunk, exists bool) {
 	chunk, exists = w.Chunks[[2]int{x, y}]
 	return
 }
 
-// return the voxel at x, y, z (global)
+// return the voxel at x, y, z (global), the caller holds readLockChunks
 func (w *World) GetVoxel(x, y, z int) (voxel Voxel, exists bool) {
-	chunk, exists := w.GetChunk(x/w.ChunkSize, y/w.ChunkSize)
+	chunkX, chunkY := floorDiv(x, w.ChunkSize), floorDiv(y, w.ChunkSize)
+	chunk, exists := w.GetChunk(chunkX, chunkY)
 	if exists {
-		voxel = chunk.GetVoxel(x%w.ChunkSize, y%w.ChunkSize, z)
+		voxel = chunk.GetVoxel(x-chunkX*w.ChunkSize, y-chunkY*w.ChunkSize, z)
 	}
 	return
 }
+
+// set the voxel at x, y, z (global) and mark its chunk as changed.
+// any edit to a loaded chunk should go through here so it gets saved.
+func (w *World) SetVoxel(x, y, z int, voxel VoxelPointer) (set bool) {
+	w.lockChunks()
+	defer w.unlockChunks()
+
+	chunkX, chunkY := floorDiv(x, w.ChunkSize), floorDiv(y, w.ChunkSize)
+	chunk, exists := w.GetChunk(chunkX, chunkY)
+	if !exists {
+		return false
+	}
+	if !chunk.SetVoxel(x-chunkX*w.ChunkSize, y-chunkY*w.ChunkSize, z, voxel) {
+		return false
+	}
+	chunk.Dirty = true
+	chunk.Edits++
+	w.Chunks[[2]int{chunkX, chunkY}] = chunk
+	return true
+}
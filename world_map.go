@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// WORLD MAP
+// the minimap and the full-screen map are drawn from one pixel per column of every chunk the player has had loaded.
+// each chunk's pixels are a tile, and tiles are kept in the save's map directory
+// so the map still shows places that have been unloaded, or were explored last time the world was open.
+
+// the full-screen map zoom levels, in screen pixels per voxel
+var mapScales = []float64{.25, .5, 1, 2, 4}
+
+// the color of anywhere that hasn't been explored
+var unexploredColor = color.RGBA{16, 16, 32, 255}
+
+// WorldMap, what the player has seen of the world from above.
+// chunks are explored from the sync goroutine and the map is drawn from the game loop, so everything goes through Mutex.
+type WorldMap struct {
+	Path      string                 // the map directory in the save, empty if the tiles aren't kept on disk
+	ChunkSize int                    // tiles are this many pixels across
+	Colors    map[string]color.RGBA  // the map color of each voxel, see loadVoxelMapColors
+	Tiles     map[[2]int]*image.RGBA // one tile per explored chunk
+	Dirty     map[[2]int]bool        // tiles that have changed since they were written
+	Version   int                    // goes up whenever a tile changes, so anything drawn from the map knows to draw it again
+	Mutex     sync.Mutex
+}
+
+// make an empty map, kept in a directory
+func newWorldMap(path string, chunkSize int, colors map[string]color.RGBA) *WorldMap {
+	return &WorldMap{
+		Path:      path,
+		ChunkSize: chunkSize,
+		Colors:    colors,
+		Tiles:     make(map[[2]int]*image.RGBA),
+		Dirty:     make(map[[2]int]bool),
+	}
+}
+
+// make the filename for a map tile
+func mapTileFileName(key [2]int) string {
+	return fmt.Sprintf("tile%v_%v.png", key[0], key[1])
+}
+
+// read the coordinates of a map tile file
+func mapTileCoordinateFromFileName(fileName string) (key [2]int, err error) {
+	_, err = fmt.Sscanf(fileName, "tile%d_%d.png", &key[0], &key[1])
+	return
+}
+
+// draw the tile for a loaded chunk, the top voxel of each column
+func (world *World) mapTile(chunk *Chunk, key [2]int, colors map[string]color.RGBA) *image.RGBA {
+	tile := image.NewRGBA(image.Rect(0, 0, chunk.Width, chunk.Height))
+	for x := 0; x < chunk.Width; x++ {
+		for y := 0; y < chunk.Height; y++ {
+			tile.SetRGBA(x, y, world.mapColumnColor(chunk, key, x, y, "blocks", colors))
+		}
+	}
+	return tile
+}
+
+// add a loaded chunk to the map, or draw it again if it has changed
+func (worldMap *WorldMap) Explore(world *World, key [2]int) {
+	world.readLockChunks()
+	chunk, exists := world.Chunks[key]
+	if !exists {
+		world.readUnlockChunks()
+		return
+	}
+	tile := world.mapTile(&chunk, key, worldMap.Colors)
+	world.readUnlockChunks()
+
+	worldMap.Mutex.Lock()
+	defer worldMap.Mutex.Unlock()
+	// reloading a chunk nobody has changed shouldn't write its tile again
+	if old, ok := worldMap.Tiles[key]; ok && bytes.Equal(old.Pix, tile.Pix) {
+		return
+	}
+	worldMap.Tiles[key] = tile
+	worldMap.Dirty[key] = true
+	worldMap.Version++
+}
+
+// draw the chunk a voxel is in again, after it has been changed
+func (worldMap *WorldMap) ExploreVoxel(world *World, x, y int) {
+	worldMap.Explore(world, [2]int{floorDiv(x, world.ChunkSize), floorDiv(y, world.ChunkSize)})
+}
+
+// has a chunk been explored
+func (worldMap *WorldMap) Explored(key [2]int) bool {
+	worldMap.Mutex.Lock()
+	defer worldMap.Mutex.Unlock()
+	_, ok := worldMap.Tiles[key]
+	return ok
+}
+
+// the map color of a column, if it has been explored
+func (worldMap *WorldMap) ColorAt(x, y int) (c color.RGBA, ok bool) {
+	worldMap.Mutex.Lock()
+	defer worldMap.Mutex.Unlock()
+	return worldMap.colorAt(x, y)
+}
+
+// ColorAt, with Mutex already held
+func (worldMap *WorldMap) colorAt(x, y int) (c color.RGBA, ok bool) {
+	key := [2]int{floorDiv(x, worldMap.ChunkSize), floorDiv(y, worldMap.ChunkSize)}
+	tile, ok := worldMap.Tiles[key]
+	if !ok {
+		return unexploredColor, false
+	}
+	return tile.RGBAAt(x-key[0]*worldMap.ChunkSize, y-key[1]*worldMap.ChunkSize), true
+}
+
+// how many times the map has changed
+func (worldMap *WorldMap) GetVersion() int {
+	worldMap.Mutex.Lock()
+	defer worldMap.Mutex.Unlock()
+	return worldMap.Version
+}
+
+// where a point in the world is on a map painted around centerX, centerY, the other way to Paint
+func mapScreenPosition(x, y, centerX, centerY, scale float64, width, height int) (screenX, screenY float64) {
+	return (x-centerX)*scale + float64(width)/2, (y-centerY)*scale + float64(height)/2
+}
+
+// fill RGBA pixels with the map around a point, at scale screen pixels per voxel.
+// x goes right and y goes down, the same as the map command, and voxels are centered on whole numbers.
+func (worldMap *WorldMap) Paint(pixels []byte, width, height int, centerX, centerY, scale float64) {
+	worldMap.Mutex.Lock()
+	defer worldMap.Mutex.Unlock()
+	for pixelY := 0; pixelY < height; pixelY++ {
+		y := int(math.Floor(centerY + .5 + (float64(pixelY)+.5-float64(height)/2)/scale))
+		for pixelX := 0; pixelX < width; pixelX++ {
+			x := int(math.Floor(centerX + .5 + (float64(pixelX)+.5-float64(width)/2)/scale))
+			c, _ := worldMap.colorAt(x, y)
+			i := (pixelY*width + pixelX) * 4
+			pixels[i], pixels[i+1], pixels[i+2], pixels[i+3] = c.R, c.G, c.B, c.A
+		}
+	}
+}
+
+// read every tile in the map directory. a missing directory is an empty map,
+// and a broken tile is left out so it gets drawn again the next time its chunk is loaded.
+func (worldMap *WorldMap) Load() error {
+	entries, err := os.ReadDir(worldMap.Path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	worldMap.Mutex.Lock()
+	defer worldMap.Mutex.Unlock()
+	for _, entry := range entries {
+		key, err := mapTileCoordinateFromFileName(entry.Name())
+		if err != nil || entry.IsDir() {
+			continue
+		}
+		tile, err := readMapTile(filepath.Join(worldMap.Path, entry.Name()))
+		if err != nil || tile.Bounds().Dx() != worldMap.ChunkSize || tile.Bounds().Dy() != worldMap.ChunkSize {
+			log.Printf("ERROR: Skipping broken map tile %s: %v", entry.Name(), err)
+			continue
+		}
+		worldMap.Tiles[key] = tile
+	}
+	worldMap.Version++
+	return nil
+}
+
+// read a map tile file
+func readMapTile(path string) (*image.RGBA, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	img, err := png.Decode(file)
+	if err != nil {
+		return nil, err
+	}
+	tile := image.NewRGBA(img.Bounds())
+	draw.Draw(tile, tile.Bounds(), img, img.Bounds().Min, draw.Src)
+	return tile, nil
+}
+
+// write every tile that has changed since it was last written
+func (worldMap *WorldMap) Save() (err error) {
+	if worldMap.Path == "" {
+		return nil
+	}
+	worldMap.Mutex.Lock()
+	defer worldMap.Mutex.Unlock()
+	if len(worldMap.Dirty) == 0 {
+		return nil
+	}
+	if err = os.MkdirAll(worldMap.Path, 0755); err != nil {
+		return err
+	}
+
+	for key := range worldMap.Dirty {
+		var data bytes.Buffer
+		if err = png.Encode(&data, worldMap.Tiles[key]); err != nil {
+			return err
+		}
+		if err = writeFileAtomic(filepath.Join(worldMap.Path, mapTileFileName(key)), data.Bytes(), 0644); err != nil {
+			return err
+		}
+		delete(worldMap.Dirty, key)
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"image/color"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// map colors that are easy to check
+var testMapColors = map[string]color.RGBA{
+	"Stone":       {100, 100, 100, 255},
+	"Cobblestone": {200, 50, 50, 255},
+}
+
+func TestWorldMapExplore(t *testing.T) {
+	world := flatTestWorld(10)
+	worldMap := newWorldMap(filepath.Join(t.TempDir(), "map"), world.ChunkSize, testMapColors)
+
+	if _, ok := worldMap.ColorAt(5, 5); ok {
+		t.Errorf("unexplored column has a color")
+	}
+	worldMap.Explore(&world, [2]int{0, 0})
+	if !worldMap.Explored([2]int{0, 0}) || worldMap.Explored([2]int{1, 0}) {
+		t.Errorf("explored the wrong chunks")
+	}
+	stone, ok := worldMap.ColorAt(5, 5)
+	if !ok || stone.A != 255 {
+		t.Errorf("explored column is %v (%v)", stone, ok)
+	}
+	if _, ok := worldMap.ColorAt(-1, 5); ok {
+		t.Errorf("column in the chunk to the left has a color")
+	}
+
+	// exploring it again without changing anything does nothing
+	version := worldMap.GetVersion()
+	worldMap.Dirty = map[[2]int]bool{}
+	worldMap.Explore(&world, [2]int{0, 0})
+	if worldMap.GetVersion() != version || len(worldMap.Dirty) != 0 {
+		t.Errorf("unchanged chunk changed the map")
+	}
+
+	// an edit shows up once its chunk is explored again
+	world.SetVoxel(5, 5, 11, defaultVoxelDictionary.GetVoxelPointerTo("Cobblestone"))
+	worldMap.ExploreVoxel(&world, 5, 5)
+	if c, _ := worldMap.ColorAt(5, 5); c == stone {
+		t.Errorf("edited column is still %v", c)
+	}
+	if worldMap.GetVersion() == version || !worldMap.Dirty[[2]int{0, 0}] {
+		t.Errorf("edit didn't change the map")
+	}
+}
+
+func TestWorldMapSaveAndLoad(t *testing.T) {
+	world := flatTestWorld(10)
+	path := filepath.Join(t.TempDir(), "map")
+	worldMap := newWorldMap(path, world.ChunkSize, testMapColors)
+
+	// nothing has been written for an empty map
+	if err := worldMap.Load(); err != nil {
+		t.Fatal(err)
+	}
+	worldMap.Explore(&world, [2]int{0, 0})
+	if err := worldMap.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if len(worldMap.Dirty) != 0 {
+		t.Errorf("saved tiles are still dirty")
+	}
+	if _, err := os.Stat(filepath.Join(path, mapTileFileName([2]int{0, 0}))); err != nil {
+		t.Errorf("tile wasn't written: %v", err)
+	}
+
+	// a broken tile is skipped, not fatal
+	if err := os.WriteFile(filepath.Join(path, mapTileFileName([2]int{-3, 4})), []byte("not a png"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := newWorldMap(path, world.ChunkSize, testMapColors)
+	if err := loaded.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Tiles) != 1 {
+		t.Fatalf("loaded %d tiles, expected 1", len(loaded.Tiles))
+	}
+	for x := 0; x < world.ChunkSize; x++ {
+		want, _ := worldMap.ColorAt(x, 7)
+		if got, ok := loaded.ColorAt(x, 7); !ok || got != want {
+			t.Errorf("loaded column %d, 7 is %v, expected %v", x, got, want)
+		}
+	}
+}
+
+func TestMapTileFileName(t *testing.T) {
+	for _, key := range [][2]int{{0, 0}, {-3, 12}, {7, -1}} {
+		got, err := mapTileCoordinateFromFileName(mapTileFileName(key))
+		if err != nil || got != key {
+			t.Errorf("%v came back as %v (%v)", key, got, err)
+		}
+	}
+	if _, err := mapTileCoordinateFromFileName("chunk0_0.json"); err == nil {
+		t.Errorf("a chunk file read as a map tile")
+	}
+}
+
+func TestWorldMapPaint(t *testing.T) {
+	world := flatTestWorld(10)
+	world.SetVoxel(20, 9, 11, defaultVoxelDictionary.GetVoxelPointerTo("Cobblestone"))
+	worldMap := newWorldMap("", world.ChunkSize, testMapColors)
+	worldMap.Explore(&world, [2]int{0, 0})
+	marked, _ := worldMap.ColorAt(20, 9)
+
+	// below 1 a pixel is more than one column, so there's no telling which one it shows
+	for _, scale := range []float64{1, 2, 4} {
+		width, height := 40, 30
+		centerX, centerY := 16.5, 12.5
+		pixels := make([]byte, width*height*4)
+		worldMap.Paint(pixels, width, height, centerX, centerY, scale)
+
+		// the pixel mapScreenPosition puts a column at is that column
+		screenX, screenY := mapScreenPosition(20, 9, centerX, centerY, scale, width, height)
+		pixelX, pixelY := int(math.Floor(screenX)), int(math.Floor(screenY))
+		i := (pixelY*width + pixelX) * 4
+		if got := (color.RGBA{pixels[i], pixels[i+1], pixels[i+2], pixels[i+3]}); got != marked {
+			t.Errorf("scale %g: pixel %d, %d is %v, expected %v", scale, pixelX, pixelY, got, marked)
+		}
+	}
+
+	// far away is unexplored
+	pixels := make([]byte, 4*4*4)
+	worldMap.Paint(pixels, 4, 4, 1000, 1000, 1)
+	if got := (color.RGBA{pixels[0], pixels[1], pixels[2], pixels[3]}); got != unexploredColor {
+		t.Errorf("unexplored pixel is %v", got)
+	}
+}
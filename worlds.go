@@ -0,0 +1,287 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// WORLDS
+// every directory in the saves directory is a world. the directory name never changes after a world is made,
+// the name shown to the player lives in world.json so worlds can be renamed freely.
+
+// where worlds are kept
+var savesPath = "save"
+
+// WorldInfo, what the world list knows about a save without loading it.
+type WorldInfo struct {
+	Path       string
+	Name       string
+	Seed       int64
+	LastPlayed time.Time
+}
+
+// list every world in a saves directory, most recently played first
+func listWorlds(savesPath string) (worlds []WorldInfo, err error) {
+	entries, err := os.ReadDir(savesPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		// hidden directories and migration backups aren't worlds
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") || strings.Contains(entry.Name(), ".backup-") {
+			continue
+		}
+		path := filepath.Join(savesPath, entry.Name())
+		metadata, err := readWorldMetadata(path)
+		if err != nil {
+			log.Printf("Skipping %s: %v", path, err)
+			continue
+		}
+
+		info := WorldInfo{Path: path, Name: metadata.Name, Seed: metadata.Seed, LastPlayed: metadata.LastPlayed}
+		if info.Name == "" {
+			// worlds from before names were saved
+			info.Name = entry.Name()
+		}
+		worlds = append(worlds, info)
+	}
+
+	sort.SliceStable(worlds, func(i, j int) bool {
+		return worlds[i].LastPlayed.After(worlds[j].LastPlayed)
+	})
+	return worlds, nil
+}
+
+// read world.json on its own
+func readWorldMetadata(savePath string) (metadata WorldMetadata, err error) {
+	data, err := os.ReadFile(filepath.Join(savePath, "world.json"))
+	if err != nil {
+		return metadata, err
+	}
+	err = json.Unmarshal(data, &metadata)
+	return metadata, err
+}
+
+// write world.json on its own
+func writeWorldMetadata(savePath string, metadata WorldMetadata) error {
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(savePath, "world.json"), data, 0644)
+}
+
+// turn a world name into a directory name that doesn't exist yet in the saves directory
+func worldDirectoryName(savesPath, name string) string {
+	var builder strings.Builder
+	for _, char := range strings.ToLower(strings.TrimSpace(name)) {
+		if (char >= 'a' && char <= 'z') || (char >= '0' && char <= '9') || char == '-' {
+			builder.WriteRune(char)
+		} else {
+			builder.WriteRune('_')
+		}
+	}
+	base := strings.Trim(builder.String(), "_")
+	if base == "" {
+		base = "world"
+	}
+
+	directoryName := base
+	for i := 2; pathExists(filepath.Join(savesPath, directoryName)); i++ {
+		directoryName = fmt.Sprintf("%s-%d", base, i)
+	}
+	return directoryName
+}
+
+// make a new world in the saves directory
+func createWorld(savesPath, name string, seed int64) (savePath string, err error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", fmt.Errorf("worlds need a name")
+	}
+	savePath = filepath.Join(savesPath, worldDirectoryName(savesPath, name))
+
+	game := &Game{}
+	game.World.Initialize(seed)
+	game.World.Name = name
+	game.World.SavePath = savePath
+	game.Player = newPlayer(Vec3{0, 0, float32(game.World.SurfaceFeaturesBeginAt) + 10})
+
+	if err = game.MakeEmptySave(); err != nil {
+		return "", err
+	}
+	if err = game.WriteData(game.Player.ToJSON()); err != nil {
+		return "", err
+	}
+	if err = writeWorldPreview(savePath, seed); err != nil {
+		// the world is still fine without a preview
+		log.Printf("ERROR: Failed to write world preview: %v", err)
+	}
+	return savePath, nil
+}
+
+// change the name shown for a world, the directory stays the same
+func renameWorld(savePath, name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return fmt.Errorf("worlds need a name")
+	}
+	lock, err := acquireSaveLock(savePath)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	metadata, err := readWorldMetadata(savePath)
+	if err != nil {
+		return err
+	}
+	metadata.Name = name
+	return writeWorldMetadata(savePath, metadata)
+}
+
+// copy a world into a new directory under a new name
+func duplicateWorld(savesPath, savePath, name string) (newPath string, err error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", fmt.Errorf("worlds need a name")
+	}
+	// hold the lock so nobody writes to it halfway through copying
+	lock, err := acquireSaveLock(savePath)
+	if err != nil {
+		return "", err
+	}
+	defer lock.Release()
+
+	newPath = filepath.Join(savesPath, worldDirectoryName(savesPath, name))
+	if err = copySaveDirectory(savePath, newPath); err != nil {
+		os.RemoveAll(newPath)
+		return "", err
+	}
+
+	metadata, err := readWorldMetadata(newPath)
+	if err != nil {
+		return "", err
+	}
+	metadata.Name = name
+	return newPath, writeWorldMetadata(newPath, metadata)
+}
+
+// delete a world for good, refuses if another instance is using it
+func deleteWorld(savePath string) error {
+	// the lock is never released, it gets deleted with the rest of the directory
+	if _, err := acquireSaveLock(savePath); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(savePath); err != nil {
+		return err
+	}
+	// backups are kept by directory name, a new world with the same name shouldn't get them
+	return os.RemoveAll(backupDirectory(savePath))
+}
+
+// draw a small top-down map of the area around spawn for the world list
+func writeWorldPreview(savePath string, seed int64) error {
+	colors, err := loadVoxelMapColors(&defaultVoxelDictionary, "assets/block_atlas.png")
+	if err != nil {
+		return err
+	}
+	// use a world of its own, renderMap generates and drops chunks as it goes
+	world := World{}
+	world.Initialize(seed)
+	return writePNG(filepath.Join(savePath, "preview.png"), world.renderMap(1, 1, "blocks", colors))
+}
+
+// lock, load and start streaming a world
+func (game *Game) OpenWorld(savePath string) (err error) {
+	lock, err := acquireSaveLock(savePath)
+	if err != nil {
+		return err
+	}
+
+	// back up before anything touches the save, migrations included
+	if _, err = createBackup(savePath, "load"); err != nil {
+		log.Printf("ERROR: Failed to back up world: %v", err)
+	}
+	game.LastBackup = time.Now()
+
+	game.World = World{}
+	game.Player = newPlayer(Vec3{})
+	game.SavedWorldData, game.SavedPlayerData = nil, nil
+	if err = game.LoadGame(savePath); err != nil {
+		lock.Release()
+		return err
+	}
+	game.SaveLock = lock
+	game.CurrentChunk = [2]int{
+		floorDiv(int(game.Player.Position.X), game.World.ChunkSize),
+		floorDiv(int(game.Player.Position.Y), game.World.ChunkSize),
+	}
+	game.setSyncState(SyncState{Center: game.CurrentChunk, Radius: game.Settings.RenderDistance, Player: game.Player.ToJSON()})
+
+	// the map of what has been explored so far, without any colors it's just the unexplored background
+	colors, err := loadVoxelMapColors(&defaultVoxelDictionary, "assets/block_atlas.png")
+	if err != nil {
+		log.Printf("ERROR: Failed to load map colors: %v", err)
+	}
+	game.Map = newWorldMap(filepath.Join(savePath, "map"), game.World.ChunkSize, colors)
+	if err = game.Map.Load(); err != nil {
+		log.Printf("ERROR: Failed to load map: %v", err)
+	}
+
+	// a broken waypoints file loses the waypoints, not the world
+	game.Waypoints, err = loadWaypoints(savePath)
+	if err != nil {
+		log.Printf("ERROR: Failed to load waypoints: %v", err)
+	}
+	game.Teleport = nil
+
+	game.World.LastPlayed = time.Now()
+	if err = game.WriteData(game.Player.ToJSON()); err != nil {
+		log.Printf("ERROR: Failed to update world metadata: %v", err)
+	}
+	if !pathExists(filepath.Join(savePath, "preview.png")) {
+		if err = writeWorldPreview(savePath, game.World.Seed); err != nil {
+			log.Printf("ERROR: Failed to write world preview: %v", err)
+		}
+	}
+
+	game.SyncStop = make(chan struct{})
+	game.SyncDone = make(chan struct{})
+	game.SyncWake = make(chan struct{}, 1)
+	go game.syncWorldWithDisk(game.SyncStop, game.SyncWake, game.SyncDone)
+	return nil
+}
+
+// stop streaming the open world, save it and let go of it
+func (game *Game) CloseWorld() {
+	if game.SaveLock == nil {
+		return
+	}
+	close(game.SyncStop)
+	<-game.SyncDone
+	game.Saves.Wait()
+
+	if err := game.SaveWorld(); err != nil {
+		log.Printf("ERROR: Failed to save world: %v", err)
+	}
+
+	// nothing else uses the world once it's gone
+	game.SaveMutex.Lock()
+	defer game.SaveMutex.Unlock()
+	game.SaveLock.Release()
+	game.SaveLock = nil
+	game.World = World{}
+	game.Map = nil
+	game.Waypoints = nil
+	game.Teleport = nil
+}
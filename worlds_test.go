@@ -0,0 +1,172 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWorldManagement(t *testing.T) {
+	savesPath := t.TempDir()
+
+	first, err := createWorld(savesPath, "My World!", 42)
+	if err != nil {
+		t.Fatalf("failed to create world: %v", err)
+	}
+	if filepath.Base(first) != "my_world" {
+		t.Errorf("directory = %s, expected my_world", filepath.Base(first))
+	}
+	if !pathExists(filepath.Join(first, "preview.png")) {
+		t.Errorf("no preview was written")
+	}
+
+	// the same name gets a directory of its own
+	second, err := createWorld(savesPath, "My World!", 7)
+	if err != nil {
+		t.Fatalf("failed to create world: %v", err)
+	}
+	if first == second {
+		t.Fatalf("both worlds were made in %s", first)
+	}
+
+	// most recently played first, and migration backups aren't listed
+	metadata, err := readWorldMetadata(second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	metadata.LastPlayed = time.Now()
+	if err := writeWorldMetadata(second, metadata); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(first+".backup-v0-20250101-120000", 0755); err != nil {
+		t.Fatal(err)
+	}
+	worlds, err := listWorlds(savesPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(worlds) != 2 || worlds[0].Path != second || worlds[1].Seed != 42 {
+		t.Fatalf("worlds = %+v, expected %s then %s", worlds, second, first)
+	}
+
+	if err := renameWorld(first, "Renamed"); err != nil {
+		t.Fatalf("failed to rename world: %v", err)
+	}
+	copied, err := duplicateWorld(savesPath, first, "Copy")
+	if err != nil {
+		t.Fatalf("failed to duplicate world: %v", err)
+	}
+	copiedMetadata, err := readWorldMetadata(copied)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if copiedMetadata.Name != "Copy" || copiedMetadata.Seed != 42 {
+		t.Errorf("copy metadata = %+v, expected Copy with seed 42", copiedMetadata)
+	}
+	if metadata, _ := readWorldMetadata(first); metadata.Name != "Renamed" {
+		t.Errorf("name = %s, expected Renamed", metadata.Name)
+	}
+
+	// worlds that are open somewhere else can't be deleted
+	lock, err := acquireSaveLock(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := deleteWorld(first); err == nil {
+		t.Errorf("deleted a world that is in use")
+	}
+	lock.Release()
+	if _, err := createBackup(first, "manual"); err != nil {
+		t.Fatal(err)
+	}
+	if err := deleteWorld(first); err != nil {
+		t.Fatalf("failed to delete world: %v", err)
+	}
+	if pathExists(first) {
+		t.Errorf("world still exists after deleting it")
+	}
+	if pathExists(backupDirectory(first)) {
+		t.Errorf("backups still exist after deleting the world, in %s", backupDirectory(first))
+	}
+
+	// a new world in the same place starts without the old one's backups
+	again, err := createWorld(savesPath, "My World!", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != first {
+		t.Fatalf("new world is at %s, expected %s", again, first)
+	}
+	if backups, _ := listBackups(again); len(backups) != 0 {
+		t.Errorf("new world has the deleted world's backups: %+v", backups)
+	}
+}
+
+func TestOpenWorld(t *testing.T) {
+	savePath, err := createWorld(t.TempDir(), "Open Me", 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// only stream the chunks right around the player, to keep the test quick
+	game := &Game{Settings: defaultSettings()}
+	game.Settings.RenderDistance = minRenderDistance
+	if err := game.OpenWorld(savePath); err != nil {
+		t.Fatalf("failed to open world: %v", err)
+	}
+	if game.World.Name != "Open Me" || game.World.LastPlayed.IsZero() {
+		t.Errorf("world = %s last played %v, expected Open Me with a last played time", game.World.Name, game.World.LastPlayed)
+	}
+	if _, err := acquireSaveLock(savePath); err == nil {
+		t.Errorf("an open world could be locked again")
+	}
+	game.CloseWorld()
+
+	// closing lets go of the lock and keeps the last played time
+	lock, err := acquireSaveLock(savePath)
+	if err != nil {
+		t.Fatalf("world is still locked after closing: %v", err)
+	}
+	lock.Release()
+	metadata, err := readWorldMetadata(savePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metadata.LastPlayed.IsZero() {
+		t.Errorf("last played time wasn't saved")
+	}
+}
+
+func TestCloseWorldWaitsForBackgroundSaves(t *testing.T) {
+	savePath, err := createWorld(t.TempDir(), "Paused", 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	game := &Game{Settings: defaultSettings()}
+	game.Settings.RenderDistance = minRenderDistance
+	if err := game.OpenWorld(savePath); err != nil {
+		t.Fatalf("failed to open world: %v", err)
+	}
+
+	// pausing and quitting straight away
+	game.Player.Position = Vec3{7, 8, 30}
+	game.SaveWorldInBackground()
+	game.Player.Position = Vec3{9, 10, 30}
+	game.CloseWorld()
+
+	// a save that starts after the world is closed has nowhere to go
+	if err := game.saveWorld(game.Player.ToJSON()); err == nil {
+		t.Errorf("saved with no world open")
+	}
+
+	// the last save is the one from closing, with the player where they were then
+	game = &Game{}
+	if err := game.LoadGame(savePath); err != nil {
+		t.Fatal(err)
+	}
+	if game.Player.Position != (Vec3{9, 10, 30}) {
+		t.Errorf("player position = %v, expected {9 10 30}", game.Player.Position)
+	}
+}
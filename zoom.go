@@ -0,0 +1,64 @@
+package main
+
+import "math"
+
+// ZOOM
+// the world is drawn at its real pixel size and then scaled up to the window by a whole number, so pixels stay square.
+// zooming changes that whole number, which changes how much of the world fits on the logical screen.
+// menus aren't zoomed, they're always laid out on a screenWidth by screenHeight screen.
+
+// the zoom levels, in order. 1 is the normal view, where the logical screen is at least screenWidth wide.
+var zoomLevels = []float64{.5, 1, 2, 3}
+
+// how many window pixels each logical pixel takes up at zoom 1, the most that still fits screenWidth across
+func baseScale(outsideWidth int) int {
+	return max(1, outsideWidth/screenWidth)
+}
+
+// how many window pixels each logical pixel takes up at a zoom level.
+// never less than 1, so zooming out stops once every window pixel is in use.
+func pixelScale(outsideWidth int, zoom float64) int {
+	return max(1, int(math.Floor(float64(baseScale(outsideWidth))*zoom)))
+}
+
+// the zoom levels that look different from each other in a window this wide.
+// a level that ends up at the same scale as the next one in is left out, e.g. .5 in windows narrower than 2 * screenWidth.
+func windowZoomLevels(outsideWidth int) []float64 {
+	var levels []float64
+	for _, level := range zoomLevels {
+		if n := len(levels); n > 0 && pixelScale(outsideWidth, levels[n-1]) == pixelScale(outsideWidth, level) {
+			levels[n-1] = level
+			continue
+		}
+		levels = append(levels, level)
+	}
+	return levels
+}
+
+// the level out of levels closest to a zoom
+func nearestLevel(levels []float64, zoom float64) float64 {
+	nearest := levels[0]
+	for _, level := range levels {
+		if math.Abs(level-zoom) < math.Abs(nearest-zoom) {
+			nearest = level
+		}
+	}
+	return nearest
+}
+
+// the zoom level closest to a zoom, used to fix a zoom in the settings file that isn't one of the levels
+func nearestZoom(zoom float64) float64 {
+	return nearestLevel(zoomLevels, zoom)
+}
+
+// the zoom the game is actually drawn at in a window this wide
+func windowZoom(outsideWidth int, zoom float64) float64 {
+	return nearestLevel(windowZoomLevels(outsideWidth), zoom)
+}
+
+// the next zoom level in or out from a zoom that does something in a window this wide, or the same zoom at the ends
+func stepZoom(zoom float64, step, outsideWidth int) float64 {
+	levels := windowZoomLevels(outsideWidth)
+	index := indexOf(levels, nearestLevel(levels, zoom))
+	return levels[clampi(index+step, 0, len(levels)-1)]
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPixelScale(t *testing.T) {
+	tests := []struct {
+		outsideWidth int
+		zoom         float64
+		want         int
+	}{
+		{1280, 1, 2},
+		{1280, .5, 1},
+		{1280, 3, 6},
+		{1000, 1, 1},
+		{640, .5, 1}, // can't go below a pixel
+		{2560, .5, 2},
+	}
+	for _, test := range tests {
+		if scale := pixelScale(test.outsideWidth, test.zoom); scale != test.want {
+			t.Errorf("pixelScale(%d, %g) = %d, want %d", test.outsideWidth, test.zoom, scale, test.want)
+		}
+	}
+}
+
+func TestWindowZoomLevels(t *testing.T) {
+	// every level left in a window looks different from the others
+	for _, width := range []int{640, 1000, 1280, 1919, 2560, 3840} {
+		scales := map[int]bool{}
+		for _, level := range windowZoomLevels(width) {
+			scale := pixelScale(width, level)
+			if scales[scale] {
+				t.Errorf("two zoom levels are at scale %d in a %d wide window", scale, width)
+			}
+			scales[scale] = true
+		}
+	}
+	if levels := windowZoomLevels(1000); !reflect.DeepEqual(levels, []float64{1, 2, 3}) {
+		t.Errorf("zoom levels in a 1000 wide window = %v, want 1, 2, 3", levels)
+	}
+	if levels := windowZoomLevels(1280); !reflect.DeepEqual(levels, zoomLevels) {
+		t.Errorf("zoom levels in a 1280 wide window = %v, want all of them", levels)
+	}
+	if zoom := windowZoom(1000, .5); zoom != 1 {
+		t.Errorf("zoom .5 in a 1000 wide window is drawn at %g, want 1", zoom)
+	}
+}
+
+func TestStepZoom(t *testing.T) {
+	if zoom := stepZoom(1, 1, 1280); zoom != 2 {
+		t.Errorf("zooming in from 1 = %g, want 2", zoom)
+	}
+	if zoom := stepZoom(.5, -1, 1280); zoom != .5 {
+		t.Errorf("zooming out from the furthest level = %g, want it to stay", zoom)
+	}
+	if zoom := stepZoom(1, -1, 1000); zoom != 1 {
+		t.Errorf("zooming out from 1 in a 1000 wide window = %g, want it to stay", zoom)
+	}
+	if zoom := nearestZoom(2.4); zoom != 2 {
+		t.Errorf("nearestZoom(2.4) = %g, want 2", zoom)
+	}
+}